@@ -12,3 +12,8 @@ var isTesting = false
 var (
 	errTypeCheck = errors.New("type check error")
 )
+
+var (
+	// ErrMaxMarks is returned when a page has more text marks than ExtractOptions.MaxMarks.
+	ErrMaxMarks = errors.New("too many text marks")
+)
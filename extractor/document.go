@@ -0,0 +1,259 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package extractor
+
+import (
+	"crypto/md5"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/unidoc/unipdf/v3/common"
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+// DocumentExtractor extracts text from all the pages of a PDF document. The fonts loaded while
+// extracting a page are cached and reused on later pages.
+// A DocumentExtractor's pages may be extracted concurrently with ExtractPageText.
+type DocumentExtractor struct {
+	reader  *model.PdfReader
+	options ExtractOptions
+
+	// fontCache is shared by the Extractors for the document's pages.
+	fontCache *fontCache
+
+	// pageCache is an LRU cache of extracted PageTexts that holds up to pageCacheSize entries. It
+	// is keyed by a hash of the pages' content streams and resources. pageLock guards it.
+	pageLock      sync.Mutex
+	pageCache     map[[md5.Size]byte]pageEntry
+	pageCacheSize int
+	pageAccess    int64 // Used to set pageEntry.access to an incrementing number.
+
+	// cmapOverrides are the CMap overrides set by SetCMapOverride. They are passed to
+	// the Extractors for the document's pages. pageLock guards them.
+	cmapOverrides map[string]*cmapOverride
+
+	// repeated is the set of marks that are repeated on many pages. It is computed when needed.
+	// repeatLock guards it and excludeRepeated.
+	repeatLock      sync.Mutex
+	repeated        map[repeatKey]bool
+	excludeRepeated bool
+}
+
+// pageEntry is an entry in the page cache.
+type pageEntry struct {
+	pt     *PageText
+	access int64
+}
+
+// NewDocumentExtractor returns a DocumentExtractor for extracting text from the pages of the PDF
+// document read by `reader` using text extraction options `options`. The options parameter can be
+// nil for the default options.
+func NewDocumentExtractor(reader *model.PdfReader, options *ExtractOptions) *DocumentExtractor {
+	d := &DocumentExtractor{
+		reader:    reader,
+		fontCache: newFontCache(),
+	}
+	if options != nil {
+		d.options = *options
+	}
+	return d
+}
+
+// ExtractAllTextFunc extracts the text from each page of the document in order and calls `cb`
+// with the page number (starting at 1) and the page's text as each page is extracted.
+// Extraction stops and the error is returned if extracting a page fails or if `cb` returns an
+// error. This allows callers to process large documents without holding all the pages' text in
+// memory and to stop early.
+func (d *DocumentExtractor) ExtractAllTextFunc(cb func(pageNum int, pt *PageText) error) error {
+	numPages, err := d.reader.GetNumPages()
+	if err != nil {
+		return err
+	}
+	for pageNum := 1; pageNum <= numPages; pageNum++ {
+		pt, err := d.ExtractPageText(pageNum)
+		if err != nil {
+			return err
+		}
+		if err := cb(pageNum, pt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetPageCacheSize sets the maximum number of pages whose extracted text is cached to `size`.
+// Pages whose content streams and resources are unchanged since they were cached are returned
+// from the cache by ExtractPageText. A size of 0, the default, disables the cache.
+// Each call returns its own copy of the cached PageText, so callers can modify it, e.g. with
+// ApplyArea, without affecting other callers.
+// NOTE: Changes to indirect objects referenced from the resources, such as fonts, are not
+// detected.
+func (d *DocumentExtractor) SetPageCacheSize(size int) {
+	d.pageLock.Lock()
+	defer d.pageLock.Unlock()
+	d.pageCacheSize = size
+	if size <= 0 {
+		d.pageCache = nil
+		return
+	}
+	if d.pageCache == nil {
+		d.pageCache = map[[md5.Size]byte]pageEntry{}
+	}
+	for len(d.pageCache) > size {
+		d.evictPage()
+	}
+}
+
+// ExtractPageText returns the text of page number `pageNum` (starting at 1) of the document.
+// Repeated text is excluded if SetExcludeRepeatedText(true) has been called.
+func (d *DocumentExtractor) ExtractPageText(pageNum int) (*PageText, error) {
+	pt, err := d.extractPageText(pageNum)
+	if err != nil {
+		return nil, err
+	}
+	d.repeatLock.Lock()
+	exclude := d.excludeRepeated
+	d.repeatLock.Unlock()
+	if !exclude {
+		return pt, nil
+	}
+	return d.removeRepeated(pt)
+}
+
+// extractPageText returns the text of page number `pageNum` (starting at 1) of the document,
+// using the page cache if it is enabled.
+func (d *DocumentExtractor) extractPageText(pageNum int) (*PageText, error) {
+	page, err := d.reader.GetPage(pageNum)
+	if err != nil {
+		return nil, err
+	}
+	e, err := d.newExtractor(page)
+	if err != nil {
+		return nil, err
+	}
+
+	d.pageLock.Lock()
+	caching := d.pageCache != nil
+	e.cmapOverrides = d.cmapOverrides
+	d.pageLock.Unlock()
+	var key [md5.Size]byte
+	if caching {
+		key = pageKey(e)
+		if pt, ok := d.getPage(key); ok {
+			return pt, nil
+		}
+	}
+
+	pt, _, _, err := e.ExtractPageText()
+	if err != nil {
+		common.Log.Debug("ERROR: ExtractPageText failed. pageNum=%d err=%v", pageNum, err)
+		return nil, err
+	}
+	if caching {
+		d.putPage(key, pt)
+	}
+	return pt, nil
+}
+
+// getPage returns the cached PageText for page cache key `key` if there is one.
+func (d *DocumentExtractor) getPage(key [md5.Size]byte) (*PageText, bool) {
+	d.pageLock.Lock()
+	defer d.pageLock.Unlock()
+	if d.pageCache == nil {
+		return nil, false
+	}
+	d.pageAccess++
+	entry, ok := d.pageCache[key]
+	if !ok {
+		return nil, false
+	}
+	entry.access = d.pageAccess
+	d.pageCache[key] = entry
+	return copyPageText(entry.pt), true
+}
+
+// putPage adds `pt` to the page cache with key `key` if the cache is enabled.
+func (d *DocumentExtractor) putPage(key [md5.Size]byte, pt *PageText) {
+	d.pageLock.Lock()
+	defer d.pageLock.Unlock()
+	if d.pageCache == nil {
+		return
+	}
+	if len(d.pageCache) >= d.pageCacheSize {
+		d.evictPage()
+	}
+	d.pageAccess++
+	d.pageCache[key] = pageEntry{pt: copyPageText(pt), access: d.pageAccess}
+}
+
+// copyPageText returns a copy of `pt` that can be modified, e.g. by ApplyArea, without changing
+// `pt`.
+func copyPageText(pt *PageText) *PageText {
+	c := *pt
+	c.marks = append([]textMark(nil), pt.marks...)
+	return &c
+}
+
+// evictPage removes the least recently used page from the page cache. d.pageLock must be held.
+func (d *DocumentExtractor) evictPage() {
+	var keys [][md5.Size]byte
+	for key := range d.pageCache {
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return d.pageCache[keys[i]].access < d.pageCache[keys[j]].access
+	})
+	delete(d.pageCache, keys[0])
+}
+
+// pageKey returns the page cache key for the page that `e` extracts text from. This is a hash of
+// the page's content streams, resources, including those inherited from the page tree, rotation,
+// MediaBox, text origin and ToUnicode CMap overrides, and of the content streams and resources of the widget annotation
+// appearance streams that are extracted with the page.
+func pageKey(e *Extractor) [md5.Size]byte {
+	h := md5.New()
+	h.Write([]byte(e.contents))
+	if e.resources != nil {
+		h.Write([]byte(e.resources.ToPdfObject().WriteString()))
+	}
+	for _, resources := range e.parentResources {
+		h.Write([]byte(resources.ToPdfObject().WriteString()))
+	}
+	fmt.Fprintf(h, "rotation=%d origin=%v mediaBox=%v", e.rotation, e.origin, e.mediaBox)
+	var names []string
+	for name := range e.cmapOverrides {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		h.Write([]byte(name))
+		h.Write(e.cmapOverrides[name].data)
+	}
+	for _, w := range e.widgets {
+		h.Write([]byte(w.contents))
+		h.Write([]byte(w.matrix.String()))
+		if w.resources != nil {
+			h.Write([]byte(w.resources.ToPdfObject().WriteString()))
+		}
+	}
+	var key [md5.Size]byte
+	copy(key[:], h.Sum(nil))
+	return key
+}
+
+// newExtractor returns an Extractor for `page` that shares the document's font cache.
+func (d *DocumentExtractor) newExtractor(page *model.PdfPage) (*Extractor, error) {
+	e, err := NewWithOptions(page, &d.options)
+	if err != nil {
+		return nil, err
+	}
+	e.fontCache = d.fontCache
+	return e, nil
+}
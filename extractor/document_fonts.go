@@ -0,0 +1,123 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package extractor
+
+import (
+	"fmt"
+
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+// FontUsage describes how a font is used in a document.
+type FontUsage struct {
+	// Font is the font. It is the font as loaded for the first page the font is used on.
+	Font *model.PdfFont
+	// BaseFont is the font's BaseFont name, e.g. "ABCDEF+Helvetica".
+	BaseFont string
+	// Subtype is the font's Subtype, e.g. "TrueType", or "Type0:CIDFontType2" for composite fonts.
+	Subtype string
+	// Embedded is true if the font's program is embedded in the document.
+	Embedded bool
+	// NumGlyphs is the number of glyphs shown in the font in the document.
+	NumGlyphs int
+	// Pages are the numbers (starting at 1) of the pages the font is used on in ascending order.
+	Pages []int
+}
+
+// String returns a string describing `u`.
+func (u FontUsage) String() string {
+	return fmt.Sprintf("{FontUsage: %q %s embedded=%t glyphs=%d pages=%v}", u.BaseFont, u.Subtype,
+		u.Embedded, u.NumGlyphs, u.Pages)
+}
+
+// FontUsageReport returns the usage of each font that shows text in the document, in the order
+// that the fonts are first used. Fonts are identified by the PDF objects they are loaded from, so a
+// font that is shared by several pages is reported once and fonts with the same name in different
+// objects are reported separately. Fonts in form XObjects and, if
+// ExtractOptions.IncludeWidgetAppearances is set, widget appearances are included. The report is
+// for pre-flight checks, font subsetting decisions and licensing audits. Making it requires
+// extracting all the pages of the document.
+func (d *DocumentExtractor) FontUsageReport() ([]FontUsage, error) {
+	numPages, err := d.reader.GetNumPages()
+	if err != nil {
+		return nil, err
+	}
+	var report []FontUsage
+	index := map[core.PdfObject]int{}     // Index in `report` of fonts loaded from objects.
+	fontIndex := map[*model.PdfFont]int{} // Index in `report` of fonts not loaded from objects.
+	for pageNum := 1; pageNum <= numPages; pageNum++ {
+		pt, err := d.extractPageText(pageNum)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range pt.fontStats {
+			i, ok := fontIndex[s.Font]
+			if s.fontObj != nil {
+				i, ok = index[s.fontObj]
+			}
+			if !ok {
+				i = len(report)
+				report = append(report, newFontUsage(s.Font, s.fontObj))
+				if s.fontObj != nil {
+					index[s.fontObj] = i
+				} else {
+					fontIndex[s.Font] = i
+				}
+			}
+			u := &report[i]
+			u.NumGlyphs += s.NumChars
+			if n := len(u.Pages); n == 0 || u.Pages[n-1] != pageNum {
+				u.Pages = append(u.Pages, pageNum)
+			}
+		}
+	}
+	return report, nil
+}
+
+// newFontUsage returns a FontUsage for `font`, loaded from `fontObj`, with no glyphs or pages.
+func newFontUsage(font *model.PdfFont, fontObj core.PdfObject) FontUsage {
+	if font == nil {
+		return FontUsage{}
+	}
+	return FontUsage{
+		Font:     font,
+		BaseFont: font.BaseFont(),
+		Subtype:  font.Subtype(),
+		Embedded: isFontEmbedded(fontObj),
+	}
+}
+
+// isFontEmbedded returns true if the font in font object `fontObj` has an embedded font program.
+// The glyphs of Type3 fonts are always in the PDF. The fonts of composite fonts are embedded if
+// their descendant fonts are.
+func isFontEmbedded(fontObj core.PdfObject) bool {
+	fontDict, ok := core.GetDict(fontObj)
+	if !ok {
+		return false
+	}
+	subtype, _ := core.GetNameVal(fontDict.Get("Subtype"))
+	switch subtype {
+	case "Type3":
+		return true
+	case "Type0":
+		descendants, ok := core.GetArray(fontDict.Get("DescendantFonts"))
+		if !ok || descendants.Len() == 0 {
+			return false
+		}
+		return isFontEmbedded(descendants.Get(0))
+	}
+	descriptor, ok := core.GetDict(fontDict.Get("FontDescriptor"))
+	if !ok {
+		return false
+	}
+	for _, key := range []core.PdfObjectName{"FontFile", "FontFile2", "FontFile3"} {
+		if descriptor.Get(key) != nil {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,114 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package extractor
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+// TestFontUsageReport checks that DocumentExtractor.FontUsageReport() reports each font object
+// once with the pages it is used on and the number of glyphs shown in it.
+func TestFontUsageReport(t *testing.T) {
+	regular := model.NewStandard14FontMustCompile(model.HelveticaName).ToPdfObject()
+	bold := model.NewStandard14FontMustCompile(model.HelveticaBoldName).ToPdfObject()
+	w := model.NewPdfWriter()
+	for _, contents := range []string{
+		`BT /F1 10 Tf 10 700 Td (One) Tj ET`,
+		`BT /F1 10 Tf 10 700 Td (Two) Tj /F2 10 Tf (Bold) Tj ET`,
+		`BT /F2 10 Tf 10 700 Td (Three) Tj ET`,
+	} {
+		page := model.NewPdfPage()
+		page.MediaBox = &fragmentMediaBox
+		page.Resources = model.NewPdfPageResources()
+		page.Resources.SetFontByName("F1", regular)
+		page.Resources.SetFontByName("F2", bold)
+		if err := page.SetContentStreams([]string{contents}, core.NewRawEncoder()); err != nil {
+			t.Fatalf("SetContentStreams failed. err=%v", err)
+		}
+		if err := w.AddPage(page); err != nil {
+			t.Fatalf("AddPage failed. err=%v", err)
+		}
+	}
+	var buf bytes.Buffer
+	if err := w.Write(&buf); err != nil {
+		t.Fatalf("Write failed. err=%v", err)
+	}
+	reader, err := model.NewPdfReader(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("NewPdfReader failed. err=%v", err)
+	}
+
+	d := NewDocumentExtractor(reader, nil)
+	report, err := d.FontUsageReport()
+	if err != nil {
+		t.Fatalf("FontUsageReport failed. err=%v", err)
+	}
+	expected := []struct {
+		baseFont  string
+		numGlyphs int
+		pages     string
+	}{
+		{"Helvetica", 6, "[1 2]"},
+		{"Helvetica-Bold", 9, "[2 3]"},
+	}
+	// Unlicensed writers add a notice to every page in a font object of its own. Those fonts are
+	// reported for the single page they are used on.
+	var fonts []FontUsage
+	for _, u := range report {
+		if len(u.Pages) > 1 {
+			fonts = append(fonts, u)
+		}
+	}
+	if len(fonts) != len(expected) {
+		t.Fatalf("%d fonts expected %d. report=%v", len(fonts), len(expected), report)
+	}
+	for i, u := range fonts {
+		exp := expected[i]
+		if u.BaseFont != exp.baseFont || u.NumGlyphs != exp.numGlyphs ||
+			fmt.Sprint(u.Pages) != exp.pages || u.Embedded {
+			t.Fatalf("font %d: %s expected %+v", i, u, exp)
+		}
+	}
+}
+
+// TestIsFontEmbedded checks that isFontEmbedded finds the font programs of simple and composite
+// fonts.
+func TestIsFontEmbedded(t *testing.T) {
+	fontFile, err := core.MakeStream(nil, core.NewRawEncoder())
+	if err != nil {
+		t.Fatalf("MakeStream failed. err=%v", err)
+	}
+	descriptor := core.MakeDict()
+	descriptor.Set("FontFile2", fontFile)
+	embedded := core.MakeDict()
+	embedded.Set("Subtype", core.MakeName("TrueType"))
+	embedded.Set("FontDescriptor", descriptor)
+	composite := core.MakeDict()
+	composite.Set("Subtype", core.MakeName("Type0"))
+	composite.Set("DescendantFonts", core.MakeArray(core.MakeIndirectObject(embedded)))
+	notEmbedded := core.MakeDict()
+	notEmbedded.Set("Subtype", core.MakeName("Type1"))
+	notEmbedded.Set("FontDescriptor", core.MakeDict())
+	for _, test := range []struct {
+		fontObj  core.PdfObject
+		expected bool
+	}{
+		{embedded, true},
+		{core.MakeIndirectObject(embedded), true},
+		{composite, true},
+		{notEmbedded, false},
+		{nil, false},
+	} {
+		if got := isFontEmbedded(test.fontObj); got != test.expected {
+			t.Fatalf("isFontEmbedded(%v)=%t expected %t", test.fontObj, got, test.expected)
+		}
+	}
+}
@@ -0,0 +1,239 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package extractor
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/unidoc/unipdf/v3/common"
+	"github.com/unidoc/unipdf/v3/core"
+)
+
+// OutlineHeading is a heading in the logical structure of a tagged PDF document.
+type OutlineHeading struct {
+	// Level is the heading level: 1 for H1 elements, 2 for H2 elements etc. Generic H elements
+	// are given the level of the nearest enclosing heading plus 1.
+	Level int
+	// Text is the heading's text.
+	Text string
+	// PageNum is the number of the page (starting at 1) that the heading is on.
+	PageNum int
+}
+
+// String returns a string describing `h`.
+func (h OutlineHeading) String() string {
+	return fmt.Sprintf("{OutlineHeading: H%d page %d %q}", h.Level, h.PageNum, h.Text)
+}
+
+// ExtractOutline returns the headings (H, H1, H2, ... H6 structure elements) in the structure
+// tree of a tagged PDF document in document order. This is the document's logical heading
+// hierarchy, which may differ from its bookmark outline. The headings' text is the text of the
+// marked content they reference. ExtractOutline returns no headings for untagged documents.
+func (d *DocumentExtractor) ExtractOutline() ([]OutlineHeading, error) {
+	trailer, err := d.reader.GetTrailer()
+	if err != nil {
+		return nil, err
+	}
+	catalog, ok := core.GetDict(trailer.Get("Root"))
+	if !ok {
+		return nil, errors.New("catalog not found")
+	}
+	root, ok := core.GetDict(catalog.Get("StructTreeRoot"))
+	if !ok {
+		common.Log.Debug("ExtractOutline: Document is not tagged.")
+		return nil, nil
+	}
+	pageNums, err := d.pageNumbers()
+	if err != nil {
+		return nil, err
+	}
+	w := structWalker{
+		roleMap:  root.Get("RoleMap"),
+		pageNums: pageNums,
+		visited:  map[*core.PdfObjectDictionary]bool{},
+	}
+	w.walk(root.Get("K"), 0, 0, nil)
+
+	texts := map[int]*PageText{}
+	var headings []OutlineHeading
+	for _, h := range w.headings {
+		pageNum := h.pageNum
+		if pageNum == 0 {
+			common.Log.Debug("ExtractOutline: Heading has no page. level=%d", h.level)
+			continue
+		}
+		pt, ok := texts[pageNum]
+		if !ok {
+			pt, err = d.ExtractPageText(pageNum)
+			if err != nil {
+				return nil, err
+			}
+			texts[pageNum] = pt
+		}
+		text := pt.mcidText(h.mcids[pageNum])
+		headings = append(headings, OutlineHeading{Level: h.level, Text: text, PageNum: pageNum})
+	}
+	return headings, nil
+}
+
+// pageNumbers returns a map of {page object number: page number} for the document's pages.
+func (d *DocumentExtractor) pageNumbers() (map[int64]int, error) {
+	numPages, err := d.reader.GetNumPages()
+	if err != nil {
+		return nil, err
+	}
+	pageNums := map[int64]int{}
+	for pageNum := 1; pageNum <= numPages; pageNum++ {
+		page, err := d.reader.GetPage(pageNum)
+		if err != nil {
+			return nil, err
+		}
+		if num, ok := objectNumber(page.GetContainingPdfObject()); ok {
+			pageNums[num] = pageNum
+		}
+	}
+	return pageNums, nil
+}
+
+// objectNumber returns the object number of indirect object or reference `obj`.
+func objectNumber(obj core.PdfObject) (int64, bool) {
+	switch t := obj.(type) {
+	case *core.PdfIndirectObject:
+		return t.ObjectNumber, true
+	case *core.PdfObjectStream:
+		return t.ObjectNumber, true
+	case *core.PdfObjectReference:
+		return t.ObjectNumber, true
+	}
+	return 0, false
+}
+
+// structHeading is a heading structure element and the marked content it references.
+type structHeading struct {
+	level   int
+	mcids   map[int]map[int]bool // {page number: {MCID: true}}
+	pageNum int                  // The first page with marked content in the heading. 0 if none.
+}
+
+// add records that `h` contains the marked content with identifier `mcid` on page `pageNum`.
+func (h *structHeading) add(pageNum, mcid int) {
+	if pageNum == 0 {
+		return
+	}
+	if h.mcids[pageNum] == nil {
+		h.mcids[pageNum] = map[int]bool{}
+	}
+	h.mcids[pageNum][mcid] = true
+	if h.pageNum == 0 {
+		h.pageNum = pageNum
+	}
+}
+
+// structWalker walks a structure tree collecting its headings.
+type structWalker struct {
+	roleMap  core.PdfObject // The structure tree's /RoleMap.
+	pageNums map[int64]int  // {page object number: page number}
+	headings []*structHeading
+	visited  map[*core.PdfObjectDictionary]bool // Guards against cycles in malformed trees.
+}
+
+// walk walks the structure tree node(s) `kids` collecting headings. `level` is the level of the
+// nearest enclosing heading, `pageNum` is the page inherited from the parent element and
+// `heading` is the heading that `kids` are in, if any.
+func (w *structWalker) walk(kids core.PdfObject, level, pageNum int, heading *structHeading) {
+	if arr, ok := core.GetArray(kids); ok {
+		for _, kid := range arr.Elements() {
+			w.walk(kid, level, pageNum, heading)
+		}
+		return
+	}
+	if mcid, ok := core.GetIntVal(kids); ok {
+		// A marked content identifier on the parent's page.
+		if heading != nil {
+			heading.add(pageNum, mcid)
+		}
+		return
+	}
+	elem, ok := core.GetDict(kids)
+	if !ok || w.visited[elem] {
+		return
+	}
+	w.visited[elem] = true
+	if p, ok := w.pageNums[pgNumber(elem)]; ok {
+		pageNum = p
+	}
+
+	if typ, _ := core.GetNameVal(elem.Get("Type")); typ == "MCR" {
+		// A marked content reference.
+		if mcid, ok := core.GetIntVal(elem.Get("MCID")); ok && heading != nil {
+			heading.add(pageNum, mcid)
+		}
+		return
+	} else if typ == "OBJR" {
+		return
+	}
+
+	if l, ok := headingLevel(w.role(elem), level); ok {
+		heading = &structHeading{level: l, mcids: map[int]map[int]bool{}}
+		w.headings = append(w.headings, heading)
+		level = l
+	}
+	w.walk(elem.Get("K"), level, pageNum, heading)
+}
+
+// pgNumber returns the object number of the /Pg entry of structure element `elem`, or 0 if it
+// doesn't have one.
+func pgNumber(elem *core.PdfObjectDictionary) int64 {
+	num, _ := objectNumber(elem.Get("Pg"))
+	return num
+}
+
+// role returns the standard structure type of structure element `elem`. Custom types are mapped
+// to standard types with the structure tree's role map.
+func (w *structWalker) role(elem *core.PdfObjectDictionary) string {
+	role, _ := core.GetNameVal(elem.Get("S"))
+	roleMap, ok := core.GetDict(w.roleMap)
+	if !ok {
+		return role
+	}
+	// Role maps can be chained. Limit the number of steps in case of cycles.
+	for i := 0; i < 10; i++ {
+		mapped, ok := core.GetNameVal(roleMap.Get(core.PdfObjectName(role)))
+		if !ok {
+			break
+		}
+		role = mapped
+	}
+	return role
+}
+
+// headingLevel returns the heading level of structure type `role` if it is a heading type.
+// `level` is the level of the nearest enclosing heading.
+func headingLevel(role string, level int) (int, bool) {
+	if role == "H" {
+		return level + 1, true
+	}
+	if len(role) == 2 && role[0] == 'H' && role[1] >= '1' && role[1] <= '6' {
+		return int(role[1] - '0'), true
+	}
+	return 0, false
+}
+
+// mcidText returns the text in `pt` with marked content identifiers in `mcids`, with runs of
+// whitespace replaced by single spaces.
+func (pt PageText) mcidText(mcids map[int]bool) string {
+	var parts []string
+	for _, tm := range pt.viewMarks {
+		if !tm.Meta && mcids[tm.mcid] {
+			parts = append(parts, tm.Text)
+		} else {
+			parts = append(parts, " ")
+		}
+	}
+	return strings.Join(strings.Fields(strings.Join(parts, "")), " ")
+}
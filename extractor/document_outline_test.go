@@ -0,0 +1,93 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package extractor
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+// taggedPDF returns a reader for a 2 page tagged PDF document. The pages have content streams
+// `contents` and are objects 3 and 4. `structure` is the document's structure tree, starting with
+// its root, which is object 7.
+func taggedPDF(t *testing.T, contents []string, structure []string) *model.PdfReader {
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R /StructTreeRoot 7 0 R /MarkInfo << /Marked true >> >>",
+		"<< /Type /Pages /Kids [3 0 R 4 0 R] /Count 2 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Contents 5 0 R " +
+			"/Resources << /Font << /F1 << /Type /Font /Subtype /Type1 /BaseFont /Courier >> >> " +
+			"/Properties << /P0 << /MCID 2 >> >> >> >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Contents 6 0 R " +
+			"/Resources << /Font << /F1 << /Type /Font /Subtype /Type1 /BaseFont /Courier >> >> >> >>",
+	}
+	for _, c := range contents {
+		objects = append(objects, fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(c)+1, c))
+	}
+	objects = append(objects, structure...)
+
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.7\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = b.Len()
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&b, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&b, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n",
+		len(objects)+1, xref)
+
+	reader, err := model.NewPdfReader(bytes.NewReader(b.Bytes()))
+	if err != nil {
+		t.Fatalf("NewPdfReader failed. err=%v", err)
+	}
+	return reader
+}
+
+// TestExtractOutline checks that DocumentExtractor.ExtractOutline() returns the headings in the
+// structure tree of a tagged PDF.
+func TestExtractOutline(t *testing.T) {
+	contents := []string{
+		`/H1 << /MCID 0 >> BDC BT /F1 20 Tf 72 700 Td (Introduction) Tj ET EMC
+		/P << /MCID 1 >> BDC BT /F1 10 Tf 72 650 Td (Some body text.) Tj ET EMC
+		/Heading /P0 BDC BT /F1 14 Tf 72 600 Td (Background and) Tj 0 -16 Td (motivation) Tj ET EMC`,
+		`/H1 << /MCID 0 >> BDC BT /F1 20 Tf 72 700 Td (Method) Tj ET EMC
+		/P << /MCID 1 >> BDC BT /F1 10 Tf 72 650 Td (More text.) Tj ET EMC`,
+	}
+	structure := []string{
+		"<< /Type /StructTreeRoot /K 8 0 R /RoleMap << /Subheading /H2 >> >>",
+		"<< /Type /StructElem /S /Document /P 7 0 R /K [9 0 R 10 0 R 11 0 R 12 0 R 13 0 R] >>",
+		"<< /Type /StructElem /S /H1 /P 8 0 R /Pg 3 0 R /K 0 >>",
+		"<< /Type /StructElem /S /P /P 8 0 R /Pg 3 0 R /K 1 >>",
+		"<< /Type /StructElem /S /Subheading /P 8 0 R /K << /Type /MCR /Pg 3 0 R /MCID 2 >> >>",
+		"<< /Type /StructElem /S /H1 /P 8 0 R /Pg 4 0 R /K [0] >>",
+		"<< /Type /StructElem /S /P /P 8 0 R /Pg 4 0 R /K 1 >>",
+	}
+	d := NewDocumentExtractor(taggedPDF(t, contents, structure), nil)
+	headings, err := d.ExtractOutline()
+	if err != nil {
+		t.Fatalf("ExtractOutline failed. err=%v", err)
+	}
+	expected := []OutlineHeading{
+		{Level: 1, Text: "Introduction", PageNum: 1},
+		{Level: 2, Text: "Background and motivation", PageNum: 1},
+		{Level: 1, Text: "Method", PageNum: 2},
+	}
+	if fmt.Sprint(headings) != fmt.Sprint(expected) {
+		t.Fatalf("headings=%v\nexpected=%v", headings, expected)
+	}
+
+	untagged := NewDocumentExtractor(testDocument(t, 1), nil)
+	if headings, err := untagged.ExtractOutline(); err != nil || len(headings) != 0 {
+		t.Fatalf("untagged document: headings=%v err=%v", headings, err)
+	}
+}
@@ -0,0 +1,170 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package extractor
+
+import (
+	"math"
+	"strings"
+
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+// SetExcludeRepeatedText sets whether ExtractPageText and ExtractAllTextFunc exclude repeated text
+// from the pages they return. Repeated text is text that is drawn at the same position on many of
+// the document's pages, such as watermarks. Lines of running headers and footers that don't
+// change between pages, e.g. that don't contain page numbers, are repeated text too. See
+// RepeatedTextMarks.
+func (d *DocumentExtractor) SetExcludeRepeatedText(exclude bool) {
+	d.repeatLock.Lock()
+	defer d.repeatLock.Unlock()
+	d.excludeRepeated = exclude
+}
+
+// RepeatedTextMarks returns the text marks on page number `pageNum` (starting at 1) that are
+// repeated text: lines of text with the same content that are drawn at the same position, within
+// repeatTol points, on at least repeatFraction of the document's pages and on at least 2 pages.
+// Whole lines are compared so that body text that happens to start at the same position on
+// several pages is not mistaken for repeated text.
+// Finding repeated text requires extracting all the pages of the document the first time it is
+// needed.
+func (d *DocumentExtractor) RepeatedTextMarks(pageNum int) (*TextMarkArray, error) {
+	pt, err := d.extractPageText(pageNum)
+	if err != nil {
+		return nil, err
+	}
+	repeated, err := d.repeatedKeys()
+	if err != nil {
+		return nil, err
+	}
+	var marks []TextMark
+	for _, line := range repeatedLines(pt, repeated) {
+		for _, tm := range line {
+			if !tm.Meta && !isTextSpace(tm.Text) {
+				marks = append(marks, tm)
+			}
+		}
+	}
+	return &TextMarkArray{marks: marks}, nil
+}
+
+const (
+	// repeatFraction is the minimum fraction of a document's pages that text must appear on to be
+	// treated as repeated text.
+	repeatFraction = 0.5
+	// repeatTol is the tolerance in points for the positions of repeated text on different pages.
+	repeatTol = 2.0
+)
+
+// repeatKey identifies lines of text that are repeated across pages. It is a line's text and the
+// position of its first mark rounded to repeatTol.
+type repeatKey struct {
+	text string
+	x, y int
+}
+
+// newRepeatKey returns the repeatKey for the line of text with marks `line`. It returns false if
+// the line has no text.
+func newRepeatKey(line []TextMark) (repeatKey, bool) {
+	var b strings.Builder
+	var first *TextMark
+	for i, tm := range line {
+		b.WriteString(tm.Text)
+		if first == nil && !tm.Meta && !isTextSpace(tm.Text) {
+			first = &line[i]
+		}
+	}
+	if first == nil {
+		return repeatKey{}, false
+	}
+	return repeatKey{
+		text: b.String(),
+		x:    int(math.Round(first.BBox.Llx / repeatTol)),
+		y:    int(math.Round(first.BBox.Lly / repeatTol)),
+	}, true
+}
+
+// repeatedLines returns the marks of the lines of `pt` whose keys are in `repeated`.
+func repeatedLines(pt *PageText, repeated map[repeatKey]bool) [][]TextMark {
+	var lines [][]TextMark
+	for _, line := range pt.viewLines() {
+		if key, ok := newRepeatKey(line); ok && repeated[key] {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// repeatedKeys returns the set of keys of the lines that are repeated across the document's
+// pages. The set is computed the first time it is needed.
+func (d *DocumentExtractor) repeatedKeys() (map[repeatKey]bool, error) {
+	d.repeatLock.Lock()
+	defer d.repeatLock.Unlock()
+	if d.repeated != nil {
+		return d.repeated, nil
+	}
+
+	numPages, err := d.reader.GetNumPages()
+	if err != nil {
+		return nil, err
+	}
+	counts := map[repeatKey]int{}
+	for pageNum := 1; pageNum <= numPages; pageNum++ {
+		pt, err := d.extractPageText(pageNum)
+		if err != nil {
+			return nil, err
+		}
+		seen := map[repeatKey]bool{}
+		for _, line := range pt.viewLines() {
+			key, ok := newRepeatKey(line)
+			if ok && !seen[key] {
+				seen[key] = true
+				counts[key]++
+			}
+		}
+	}
+	minPages := int(math.Max(2, math.Ceil(repeatFraction*float64(numPages))))
+	repeated := map[repeatKey]bool{}
+	for key, n := range counts {
+		if n >= minPages {
+			repeated[key] = true
+		}
+	}
+	d.repeated = repeated
+	return repeated, nil
+}
+
+// removeRepeated returns a copy of `pt` without the document's repeated text.
+func (d *DocumentExtractor) removeRepeated(pt *PageText) (*PageText, error) {
+	repeated, err := d.repeatedKeys()
+	if err != nil {
+		return nil, err
+	}
+	// glyphKey identifies a mark on the page by its text and position.
+	type glyphKey struct {
+		text string
+		bbox model.PdfRectangle
+	}
+	remove := map[glyphKey]bool{}
+	for _, line := range repeatedLines(pt, repeated) {
+		for _, tm := range line {
+			if !tm.Meta {
+				remove[glyphKey{tm.Text, tm.BBox}] = true
+			}
+		}
+	}
+	clean := *pt
+	clean.marks = nil
+	clean.index = &markIndex{}
+	for _, tm := range pt.marks {
+		mark := tm.ToTextMark()
+		if !remove[glyphKey{mark.Text, mark.BBox}] {
+			clean.marks = append(clean.marks, tm)
+		}
+	}
+	clean.computeViews()
+	procBuf(&clean)
+	return &clean, nil
+}
@@ -0,0 +1,83 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package extractor
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/unidoc/unipdf/v3/creator"
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+// TestExcludeRepeatedText checks that DocumentExtractor finds and excludes text that is drawn at
+// the same position on every page, and keeps body text that starts at the same position on every
+// page but differs between pages.
+func TestExcludeRepeatedText(t *testing.T) {
+	bodies := []string{"The cat sat", "The dog ran", "A bird flew"}
+	numPages := len(bodies)
+	c := creator.New()
+	for _, text := range bodies {
+		c.NewPage()
+		watermark := c.NewParagraph("DRAFT")
+		watermark.SetPos(250, 400)
+		body := c.NewParagraph(text)
+		body.SetPos(50, 100)
+		for _, p := range []*creator.Paragraph{watermark, body} {
+			if err := c.Draw(p); err != nil {
+				t.Fatalf("Draw failed. err=%v", err)
+			}
+		}
+	}
+	var buf bytes.Buffer
+	if err := c.Write(&buf); err != nil {
+		t.Fatalf("Write failed. err=%v", err)
+	}
+	reader, err := model.NewPdfReader(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("NewPdfReader failed. err=%v", err)
+	}
+
+	d := NewDocumentExtractor(reader, nil)
+	marks, err := d.RepeatedTextMarks(2)
+	if err != nil {
+		t.Fatalf("RepeatedTextMarks failed. err=%v", err)
+	}
+	pt, err := d.ExtractPageText(2)
+	if err != nil {
+		t.Fatalf("ExtractPageText failed. err=%v", err)
+	}
+	var body model.PdfRectangle
+	for _, line := range pt.Lines() {
+		if line.Text == bodies[1] {
+			body = line.BBox
+		}
+	}
+	text := ""
+	for _, tm := range marks.Elements() {
+		text += tm.Text
+		if overlapArea(tm.BBox, body) > 0 {
+			t.Fatalf("body mark %s is repeated text", tm)
+		}
+	}
+	// Unlicensed creators add a notice to every page, which is also repeated text.
+	if !strings.HasPrefix(text, "DRAFT") {
+		t.Fatalf("repeated text=%q expected %q", text, "DRAFT")
+	}
+
+	d.SetExcludeRepeatedText(true)
+	for pageNum := 1; pageNum <= numPages; pageNum++ {
+		pt, err := d.ExtractPageText(pageNum)
+		if err != nil {
+			t.Fatalf("ExtractPageText failed. err=%v", err)
+		}
+		expected := bodies[pageNum-1]
+		if pt.Text() != expected {
+			t.Fatalf("page %d: text=%q expected %q", pageNum, pt.Text(), expected)
+		}
+	}
+}
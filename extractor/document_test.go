@@ -0,0 +1,203 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package extractor
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/creator"
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+// testDocument returns a PdfReader for a PDF document with `numPages` pages. Page i contains the
+// text "Page i".
+func testDocument(t *testing.T, numPages int) *model.PdfReader {
+	c := creator.New()
+	for i := 1; i <= numPages; i++ {
+		c.NewPage()
+		if err := c.Draw(c.NewParagraph(fmt.Sprintf("Page %d", i))); err != nil {
+			t.Fatalf("Draw failed. err=%v", err)
+		}
+	}
+	var buf bytes.Buffer
+	if err := c.Write(&buf); err != nil {
+		t.Fatalf("Write failed. err=%v", err)
+	}
+	reader, err := model.NewPdfReader(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("NewPdfReader failed. err=%v", err)
+	}
+	return reader
+}
+
+// TestExtractAllTextFunc checks that DocumentExtractor.ExtractAllTextFunc calls back for each page
+// in order and stops when the callback returns an error.
+func TestExtractAllTextFunc(t *testing.T) {
+	d := NewDocumentExtractor(testDocument(t, 3), nil)
+	var pageNums []int
+	err := d.ExtractAllTextFunc(func(pageNum int, pt *PageText) error {
+		pageNums = append(pageNums, pageNum)
+		expected := fmt.Sprintf("Page %d", pageNum)
+		if !strings.Contains(pt.Text(), expected) {
+			t.Fatalf("page %d: text=%q doesn't contain %q", pageNum, pt.Text(), expected)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ExtractAllTextFunc failed. err=%v", err)
+	}
+	if fmt.Sprint(pageNums) != "[1 2 3]" {
+		t.Fatalf("pageNums=%v expected [1 2 3]", pageNums)
+	}
+	if len(d.fontCache.fonts) == 0 {
+		t.Fatalf("font cache is empty")
+	}
+
+	errStop := errors.New("stop")
+	pageNums = nil
+	err = d.ExtractAllTextFunc(func(pageNum int, pt *PageText) error {
+		pageNums = append(pageNums, pageNum)
+		if pageNum == 2 {
+			return errStop
+		}
+		return nil
+	})
+	if err != errStop {
+		t.Fatalf("err=%v expected %v", err, errStop)
+	}
+	if fmt.Sprint(pageNums) != "[1 2]" {
+		t.Fatalf("pageNums=%v expected [1 2]", pageNums)
+	}
+}
+
+// TestPageCache checks that DocumentExtractor caches the text of unchanged pages.
+func TestPageCache(t *testing.T) {
+	reader := testDocument(t, 3)
+	d := NewDocumentExtractor(reader, nil)
+	d.SetPageCacheSize(2)
+
+	pt1, err := d.ExtractPageText(1)
+	if err != nil {
+		t.Fatalf("ExtractPageText failed. err=%v", err)
+	}
+	// Cached pages are returned as copies that share the views of the cached PageText.
+	pt, _ := d.ExtractPageText(1)
+	if pt == pt1 || len(pt.viewMarks) == 0 || &pt.viewMarks[0] != &pt1.viewMarks[0] {
+		t.Fatalf("page 1 was not cached")
+	}
+	// Restricting a copy to an area doesn't change the cached page.
+	pt.ApplyArea(model.PdfRectangle{})
+	if pt.Text() != "" {
+		t.Fatalf("ApplyArea didn't remove the text. text=%q", pt.Text())
+	}
+	if pt, _ := d.ExtractPageText(1); pt.Text() != pt1.Text() {
+		t.Fatalf("ApplyArea changed the cached page. text=%q expected %q", pt.Text(), pt1.Text())
+	}
+
+	page, err := reader.GetPage(1)
+	if err != nil {
+		t.Fatalf("GetPage failed. err=%v", err)
+	}
+	contents := `BT /UniDocCourier 10 Tf 10 700 Td (Changed) Tj ET`
+	page.Resources = fragmentResources()
+	if err := page.SetContentStreams([]string{contents}, core.NewRawEncoder()); err != nil {
+		t.Fatalf("SetContentStreams failed. err=%v", err)
+	}
+	pt, err = d.ExtractPageText(1)
+	if err != nil {
+		t.Fatalf("ExtractPageText failed. err=%v", err)
+	}
+	if pt.Text() != "Changed" {
+		t.Fatalf("changed page was returned from the cache. text=%q", pt.Text())
+	}
+
+	for pageNum := 2; pageNum <= 3; pageNum++ {
+		if _, err := d.ExtractPageText(pageNum); err != nil {
+			t.Fatalf("ExtractPageText failed. err=%v", err)
+		}
+	}
+	if len(d.pageCache) != 2 {
+		t.Fatalf("%d pages cached expected 2", len(d.pageCache))
+	}
+}
+
+// TestPageCacheRotation checks that pages that differ only in their rotation are not returned
+// from the page cache for each other.
+func TestPageCacheRotation(t *testing.T) {
+	reader := testDocument(t, 2)
+	d := NewDocumentExtractor(reader, nil)
+	d.SetPageCacheSize(2)
+	contents := `BT /UniDocCourier 10 Tf 10 700 Td (Same) Tj ET`
+	for pageNum, rotate := range []int64{0, 90} {
+		page, err := reader.GetPage(pageNum + 1)
+		if err != nil {
+			t.Fatalf("GetPage failed. err=%v", err)
+		}
+		page.Resources = fragmentResources()
+		rotate := rotate
+		page.Rotate = &rotate
+		if err := page.SetContentStreams([]string{contents}, core.NewRawEncoder()); err != nil {
+			t.Fatalf("SetContentStreams failed. err=%v", err)
+		}
+	}
+	for pageNum, rotation := range []int{0, 90} {
+		pt, err := d.ExtractPageText(pageNum + 1)
+		if err != nil {
+			t.Fatalf("ExtractPageText failed. err=%v", err)
+		}
+		if pt.Rotation() != rotation {
+			t.Fatalf("page %d: Rotation=%d expected %d", pageNum+1, pt.Rotation(), rotation)
+		}
+	}
+}
+
+// TestConcurrentExtraction checks that extracting the pages of a document concurrently gives the
+// same results as extracting them sequentially. Run it with -race to check for data races.
+func TestConcurrentExtraction(t *testing.T) {
+	const numPages = 8
+	reader := testDocument(t, numPages)
+	expected := make([]string, numPages)
+	err := NewDocumentExtractor(reader, nil).ExtractAllTextFunc(func(pageNum int, pt *PageText) error {
+		expected[pageNum-1] = pt.Text()
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ExtractAllTextFunc failed. err=%v", err)
+	}
+
+	d := NewDocumentExtractor(reader, nil)
+	d.SetPageCacheSize(numPages / 2)
+	texts := make([]string, numPages)
+	errs := make([]error, numPages)
+	var wg sync.WaitGroup
+	for i := 0; i < numPages; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			pt, err := d.ExtractPageText(i + 1)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			texts[i] = pt.Text()
+		}(i)
+	}
+	wg.Wait()
+	for i := range texts {
+		if errs[i] != nil {
+			t.Fatalf("page %d: ExtractPageText failed. err=%v", i+1, errs[i])
+		}
+		if texts[i] != expected[i] {
+			t.Fatalf("page %d: text=%q expected %q", i+1, texts[i], expected[i])
+		}
+	}
+}
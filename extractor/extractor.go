@@ -6,6 +6,11 @@
 package extractor
 
 import (
+	"fmt"
+
+	"github.com/unidoc/unipdf/v3/common"
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/internal/transform"
 	"github.com/unidoc/unipdf/v3/model"
 )
 
@@ -15,26 +20,242 @@ type Extractor struct {
 	contents  string
 	resources *model.PdfPageResources
 
-	// fontCache is a simple LRU cache that is used to prevent redundant constructions of PdfFont's from
-	// PDF objects. NOTE: This is not a conventional glyph cache. It only caches PdfFont's.
-	fontCache map[string]fontEntry
+	// parentResources are the resources of the page's ancestors in the page tree, nearest first.
+	// Fonts that are not in the page's resources are looked up in them.
+	parentResources []*model.PdfPageResources
+
+	// cmapOverrides are the CMaps that replace the encodings of fonts, keyed by font name. They
+	// are set by SetCMapOverride.
+	cmapOverrides map[string]*cmapOverride
+
+	// fontCache caches the fonts used on the page. It may be shared with other pages.
+	fontCache *fontCache
 
 	// text results from running extractXYText on forms within the page.
 	// TODO(peterwilliams): Cache this map accross all pages in a PDF to speed up processig.
-	formResults map[string]textResult
+	formResults map[formKey]textResult
 
-	// accessCount is used to set fontEntry.access to an incrementing number.
-	accessCount int64
+	// formStack is the set of form XObjects whose content streams are being extracted. It is used
+	// to detect forms that draw themselves.
+	formStack map[*core.PdfObjectStream]bool
 
 	// textCount is an incrementing number used to identify XYTest objects.
 	textCount int64
+
+	// numMarks is the number of text marks extracted by the current ExtractPageText call.
+	numMarks int
+
+	// rotation is the page's /Rotate value in degrees, normalized to [0, 360).
+	rotation int
+
+	// origin is the lower left corner of the page box that text positions are measured from.
+	origin transform.Point
+
+	// mediaBox is the MediaBox of the page. It is the zero rectangle if the page has no valid
+	// MediaBox.
+	mediaBox model.PdfRectangle
+
+	// widgets are the appearance streams of the page's widget annotations. They are only loaded
+	// if ExtractOptions.IncludeWidgetAppearances is set.
+	widgets []widgetAppearance
+
+	options ExtractOptions
+}
+
+// ExtractOptions contains options for controlling text extraction from PDF pages.
+type ExtractOptions struct {
+	// MaxMarks is the maximum number of text marks that will be extracted from a page. Extraction
+	// stops with ErrMaxMarks if a page has more marks than this. This bounds the memory used to
+	// extract text from untrusted PDFs. A value of 0 means no limit.
+	MaxMarks int
+
+	// LineSeparator is inserted between lines in the extracted text. The inserted separators are
+	// Meta TextMarks so the TextMark offsets stay consistent with the text. The default is "\n".
+	LineSeparator string
+
+	// UseCropBox measures text positions from the lower left corner of the page's CropBox rather
+	// than from the origin of the PDF user space. This makes the extracted coordinates match what
+	// a user sees in a viewer when a page has a CropBox that is smaller than its MediaBox. The
+	// MediaBox is used if the page has no CropBox.
+	UseCropBox bool
+
+	// SplitLigatures emits a separate TextMark for each rune of glyphs that map to several runes,
+	// such as ligatures like "ﬁ" that are extracted as "fi". The runes divide the glyph's bounding
+	// box equally. This gives one TextMark per rune for building character level indexes. By
+	// default these glyphs are extracted as a single TextMark.
+	SplitLigatures bool
+
+	// DetectTJTabs inserts a tab rather than a space in the extracted text where a TJ array moves
+	// the text position to the right by 2 ems or more. Some PDFs lay out tables with such jumps
+	// rather than with separate text positioning operators.
+	DetectTJTabs bool
+
+	// IncludeWidgetAppearances adds the text drawn by the appearance streams of the page's visible
+	// widget annotations to the extracted text, at the annotations' positions on the page. Filled
+	// form field values are drawn by these appearance streams rather than by the page contents.
+	IncludeWidgetAppearances bool
+
+	// InferredSpace is the text of the spaces that are inserted where the gap between two text
+	// marks indicates a space between words. Spaces that are drawn in the PDF are not affected.
+	// Setting it to a character that doesn't occur in the text, such as "\u2423", lets callers
+	// tell inferred spaces from literal ones, which matters when literal spacing is authoritative
+	// as in code listings. The inserted spaces are Meta TextMarks with Original " ". The default
+	// is " ".
+	InferredSpace string
+
+	// ColumnGutterWidth is the minimum width, in points, of the vertical gutters of white space
+	// between columns of text that PageText.Columns() splits pages at. The default is 1.5 times
+	// the page's dominant font size. Larger values avoid splitting pages at wide gaps in tables or
+	// forms. Smaller values find columns that are close together.
+	ColumnGutterWidth float64
+
+	// KeepControlChars keeps glyphs whose text is NUL ('\x00'). By default these are skipped, as
+	// is the space they take up on the page. This gives every code point the PDF emits, which is
+	// useful for diagnosing encoding problems and for exact extraction. Control characters are
+	// flagged by TextMark.Control.
+	KeepControlChars bool
+
+	// UnicodeScripts replaces the text of superscripts and subscripts that have Unicode forms
+	// with those forms, e.g. "x2" drawn with a raised "2" is extracted as "x²" and "H2O" drawn
+	// with a lowered "2" as "H₂O". These marks are put in the line of the text they are attached
+	// to. Scripts without Unicode forms are extracted unchanged and can be found with
+	// TextMark.Script.
+	UnicodeScripts bool
+
+	// CoordinateTransform is a PDF matrix [a b c d e f] that maps the PDF coordinates of the page,
+	// in points from the MediaBox or CropBox origin, to the coordinates that the extractor returns.
+	// It is applied to all text positions, bounding boxes, rulings and images, so results can be
+	// returned directly in the coordinates of a rendered image of the page. e.g.
+	// [2 0 0 -2 0 1584] gives pixel coordinates at 144 DPI, with the origin at the top left, for
+	// a page 792 points high. Bounding boxes are normalized so that Llx <= Urx and Lly <= Ury.
+	// The default, nil, leaves coordinates in points. Matrices that don't have 6 elements are
+	// ignored.
+	CoordinateTransform []float64
+
+	// ExcludeInvisibleText skips text that is neither filled nor stroked, i.e. text drawn with
+	// render modes Tr 3 and Tr 7. See RenderMode.Invisible. By default this text is extracted
+	// and can be identified by TextMark.RenderMode. Text that is also added to the clipping path
+	// is extracted if it is filled or stroked (Tr 4, 5 and 6).
+	ExcludeInvisibleText bool
+
+	// OverprintTolerance is the largest distance between two marks with the same text in a line,
+	// as a fraction of the average character width, at which the marks are taken to be the same
+	// glyph drawn more than once. Some PDFs draw each glyph several times with small offsets to
+	// simulate bold text or anti-aliasing. All but one of these copies are removed so the text
+	// isn't duplicated. Marks drawn with text knockout off are never removed. The default is 0.3.
+	// Negative values keep all the copies.
+	OverprintTolerance float64
+
+	// ReadingOrder is the order that the text of pages is laid out in. It sets the order of the
+	// text returned by PageText.Text() and of the marks returned by PageText.Marks(). The default
+	// is ReadingOrderLines.
+	ReadingOrder ReadingOrder
+
+	// TileMarks is the number of text marks above which a page is laid out in tiles. The page is
+	// split into a grid of tiles that each hold about TileMarks marks and the text of each tile is
+	// laid out separately, in the order of ReadingOrder. The tiles are ordered in rows from top to
+	// bottom and left to right in each row. This bounds the time and memory used by pages with
+	// hundreds of thousands of marks, such as the labels of maps and CAD drawings, but lines of
+	// text that cross tile boundaries are split. A value of 0, the default, disables tiling.
+	TileMarks int
+}
+
+// ReadingOrder is an algorithm for ordering the text on a page. See ExtractOptions.ReadingOrder.
+type ReadingOrder int
+
+// Reading orders.
+const (
+	// ReadingOrderLines orders the lines of text on a page top to bottom and the text in each
+	// line left to right. Text in other orientations follows, grouped by orientation.
+	ReadingOrderLines ReadingOrder = iota
+	// ReadingOrderXYCut splits the page into blocks with the recursive XY-cut algorithm and
+	// orders the blocks as PageText.TextXYCut() does. The text in each block is ordered as in
+	// ReadingOrderLines. This keeps the text of columns together.
+	ReadingOrderXYCut
+)
+
+// String returns a string describing `order`.
+func (order ReadingOrder) String() string {
+	switch order {
+	case ReadingOrderLines:
+		return "Lines"
+	case ReadingOrderXYCut:
+		return "XYCut"
+	}
+	return fmt.Sprintf("ReadingOrder(%d)", int(order))
+}
+
+// coordinateTransform returns the matrix of CoordinateTransform or the identity matrix if it
+// isn't set or is invalid.
+func (opts ExtractOptions) coordinateTransform() transform.Matrix {
+	m := opts.CoordinateTransform
+	if len(m) != 6 {
+		if m != nil {
+			common.Log.Debug("ERROR: CoordinateTransform must have 6 elements. Ignoring %v", m)
+		}
+		return transform.IdentityMatrix()
+	}
+	return transform.NewMatrix(m[0], m[1], m[2], m[3], m[4], m[5])
+}
+
+// inferredSpace returns the text of the spaces inserted between words that are separated by gaps.
+func (opts ExtractOptions) inferredSpace() string {
+	if opts.InferredSpace == "" {
+		return spaceMark.Text
+	}
+	return opts.InferredSpace
+}
+
+// overprintTolerance returns the OverprintTolerance used to remove duplicate glyphs.
+func (opts ExtractOptions) overprintTolerance() float64 {
+	if opts.OverprintTolerance == 0 {
+		return defaultOverprintTolerance
+	}
+	return opts.OverprintTolerance
+}
+
+// defaultOverprintTolerance is the default ExtractOptions.OverprintTolerance.
+// NOTE(peterwilliams97) 0.3 is a guess. It may be possible to tune this to a better value.
+const defaultOverprintTolerance = 0.3
+
+// lineSeparator returns the separator that is inserted between lines of extracted text.
+func (opts ExtractOptions) lineSeparator() string {
+	if opts.LineSeparator == "" {
+		return lineJoiner
+	}
+	return opts.LineSeparator
 }
 
 // New returns an Extractor instance for extracting content from the input PDF page.
 func New(page *model.PdfPage) (*Extractor, error) {
+	return NewWithOptions(page, nil)
+}
+
+// NewWithOptions returns an Extractor instance for extracting content from the input PDF page
+// using text extraction options `options`. The options parameter can be nil for the default
+// options.
+func NewWithOptions(page *model.PdfPage, options *ExtractOptions) (*Extractor, error) {
+	e := &Extractor{
+		fontCache:   newFontCache(),
+		formResults: map[formKey]textResult{},
+	}
+	if options != nil {
+		e.options = *options
+	}
+	if err := e.Reset(page); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// Reset sets up `e` to extract content from `page` with the same options. The font cache is kept,
+// so fonts that are shared by pages are only loaded once, and the other per-page state is cleared.
+// This avoids reallocating an Extractor for each page when processing the pages of a document in
+// order. If Reset returns an error, `e` is unchanged.
+func (e *Extractor) Reset(page *model.PdfPage) error {
 	contents, err := page.GetAllContentStreams()
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	// Uncomment these lines to see the contents of the page. For debugging.
@@ -42,11 +263,95 @@ func New(page *model.PdfPage) (*Extractor, error) {
 	// fmt.Printf("%s\n", contents)
 	// fmt.Println("========================= ::: =========================")
 
-	e := &Extractor{
+	var origin transform.Point
+	if e.options.UseCropBox {
+		box := page.CropBox
+		if box == nil {
+			box, err = page.GetMediaBox()
+			if err != nil {
+				return err
+			}
+		}
+		origin = transform.Point{X: box.Llx, Y: box.Lly}
+	}
+	rotate, err := page.GetRotate()
+	if err != nil {
+		common.Log.Debug("ERROR: Invalid page rotation. err=%v", err)
+	}
+
+	e.contents = contents
+	e.resources = page.Resources
+	e.parentResources = parentResources(page)
+	e.origin = origin
+	e.rotation = int((rotate%360 + 360) % 360)
+	e.mediaBox = model.PdfRectangle{}
+	if mediaBox, err := page.GetMediaBox(); err == nil {
+		e.mediaBox = *mediaBox
+	}
+	e.widgets = nil
+	if e.options.IncludeWidgetAppearances {
+		e.widgets = widgetAppearances(page)
+	}
+	// The cached forms' marks are positioned on this page.
+	for key := range e.formResults {
+		delete(e.formResults, key)
+	}
+	if e.formResults == nil {
+		e.formResults = map[formKey]textResult{}
+	}
+	if e.fontCache == nil {
+		e.fontCache = newFontCache()
+	}
+	e.numMarks = 0
+	return nil
+}
+
+// parentResources returns the resources of the ancestors of `page` in the page tree, nearest
+// first. Invalid resources are skipped.
+func parentResources(page *model.PdfPage) []*model.PdfPageResources {
+	var resources []*model.PdfPageResources
+	visited := map[*core.PdfObjectDictionary]bool{}
+	for node := page.Parent; node != nil; {
+		dict, ok := core.GetDict(node)
+		if !ok || visited[dict] {
+			break
+		}
+		visited[dict] = true
+		if resDict, ok := core.GetDict(dict.Get("Resources")); ok {
+			res, err := model.NewPdfPageResourcesFromDict(resDict)
+			if err != nil {
+				common.Log.Debug("ERROR: Invalid parent resources. err=%v", err)
+			} else {
+				resources = append(resources, res)
+			}
+		}
+		node = dict.Get("Parent")
+	}
+	return resources
+}
+
+// NewFromContents returns an Extractor for extracting text from content stream `contents` with
+// resources `resources`, which are drawn on a page with MediaBox `mediaBox`. This allows content
+// stream fragments, such as the contents of a form XObject, to be processed without a page.
+func NewFromContents(contents string, resources *model.PdfPageResources,
+	mediaBox model.PdfRectangle) *Extractor {
+	return &Extractor{
 		contents:    contents,
-		resources:   page.Resources,
-		fontCache:   map[string]fontEntry{},
-		formResults: map[string]textResult{},
+		resources:   resources,
+		mediaBox:    mediaBox,
+		fontCache:   newFontCache(),
+		formResults: map[formKey]textResult{},
 	}
-	return e, nil
+}
+
+// addMarks records that `n` more text marks have been extracted from the page and returns
+// ErrMaxMarks if this takes the page over the ExtractOptions.MaxMarks limit.
+func (e *Extractor) addMarks(n int) error {
+	e.numMarks += n
+	if e.options.MaxMarks > 0 && e.numMarks > e.options.MaxMarks {
+		common.Log.Debug("ERROR: Too many text marks. numMarks=%d MaxMarks=%d",
+			e.numMarks, e.options.MaxMarks)
+		return ErrMaxMarks
+	}
+	return nil
 }
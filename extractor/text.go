@@ -8,14 +8,17 @@ package extractor
 import (
 	"errors"
 	"fmt"
+	"image/color"
 	"math"
 	"sort"
 	"strings"
+	"sync"
 	"unicode"
 
 	"github.com/unidoc/unipdf/v3/common"
 	"github.com/unidoc/unipdf/v3/contentstream"
 	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/internal/textencoding"
 	"github.com/unidoc/unipdf/v3/internal/transform"
 	"github.com/unidoc/unipdf/v3/model"
 	"golang.org/x/text/unicode/norm"
@@ -47,11 +50,38 @@ func (e *Extractor) ExtractTextWithStats() (extracted string, numChars int, numM
 
 // ExtractPageText returns the text contents of `e` (an Extractor for a page) as a PageText.
 func (e *Extractor) ExtractPageText() (*PageText, int, int, error) {
-	pt, numChars, numMisses, err := e.extractPageText(e.contents, e.resources, transform.IdentityMatrix(), 0)
+	e.numMarks = 0
+	// Text positions are measured from `e.origin` and then transformed to the caller's
+	// coordinates.
+	pageCTM := e.options.coordinateTransform().Mult(
+		translationMatrix(transform.Point{X: -e.origin.X, Y: -e.origin.Y}))
+	pt, numChars, numMisses, err := e.extractPageText(e.contents, e.resources, pageCTM, 0)
 	if err != nil {
 		return nil, numChars, numMisses, err
 	}
+	for _, w := range e.widgets {
+		wt, wChars, wMisses, err := e.extractPageText(w.contents, w.resources,
+			pageCTM.Mult(w.matrix), 1)
+		numChars += wChars
+		numMisses += wMisses
+		if err != nil {
+			return nil, numChars, numMisses, err
+		}
+		pt.marks = append(pt.marks, wt.marks...)
+		pt.fontStats = mergeFontDecodeStats(pt.fontStats, wt.fontStats)
+		pt.rulings = append(pt.rulings, wt.rulings...)
+		pt.images = append(pt.images, wt.images...)
+	}
+	for i := range pt.marks {
+		pt.marks[i].serial = i
+	}
+	pt.options = e.options
+	pt.rotation = e.rotation
+	if e.mediaBox != (model.PdfRectangle{}) {
+		pt.mediaBox = transformRect(pageCTM, e.mediaBox)
+	}
 	pt.computeViews()
+	pt.index = &markIndex{}
 	procBuf(pt)
 
 	return pt, numChars, numMisses, err
@@ -65,9 +95,18 @@ func (e *Extractor) extractPageText(contents string, resources *model.PdfPageRes
 	common.Log.Trace("extractPageText: level=%d", level)
 	pageText := &PageText{}
 	state := newTextState()
-	fontStack := fontStacker{}
-	to := newTextObject(e, resources, contentstream.GraphicsState{}, &state, &fontStack)
+	// savedStates is the stack of text states saved by q operators.
+	var savedStates []textState
+	to := newTextObject(e, resources, contentstream.GraphicsState{}, &state)
 	var inTextObj bool
+	// inPhantomObj is true when text is being shown outside a text object. Some PDF generators
+	// do this. The text is shown in a phantom text object that starts at the first text showing
+	// operator outside a text object and ends at the next BT or ET.
+	var inPhantomObj bool
+	// mcStack is the stack of marked content sequences that the current operator is in.
+	var mcStack []markedContent
+	// path is the current path. It is used to find rulings.
+	var path pathBuilder
 
 	cstreamParser := contentstream.NewContentStreamParser(contents)
 	operations, err := cstreamParser.Parse()
@@ -84,44 +123,74 @@ func (e *Extractor) extractPageText(contents string, resources *model.PdfPageRes
 
 			operand := op.Operand
 
+			if !inTextObj && !inPhantomObj && isShowTextOperand(operand) {
+				common.Log.Debug("%#q outside a text object. Using a phantom text object.", operand)
+				pageText.marks = append(pageText.marks, to.marks...)
+				inPhantomObj = true
+
+				graphicsState := gs
+				graphicsState.CTM = parentCTM.Mult(graphicsState.CTM)
+				to = newTextObject(e, resources, graphicsState, &state)
+			}
+
+			// Colors can be changed inside text objects so the text object keeps track of the
+			// current colors.
+			to.setColors(gs)
+			// Marked content sequences can start and end inside and outside text objects.
+			to.mcid = currentMCID(mcStack)
+			to.lang = currentLang(mcStack)
+			to.actualText = currentActualText(mcStack)
+
 			switch operand {
-			case "q":
-				if !fontStack.empty() {
-					common.Log.Trace("Save font state: %s\n%s",
-						fontStack.peek(), fontStack.String())
-					fontStack.push(fontStack.peek())
-				}
-				if state.tfont != nil {
-					common.Log.Trace("Save font state: %s\n->%s\n%s",
-						fontStack.peek(), state.tfont, fontStack.String())
-					fontStack.push(state.tfont)
+			case "m", "l", "c", "v", "y", "re", "h", // Path construction.
+				"S", "s", "f", "F", "f*", "B", "B*", "b", "b*", "n", // Path painting.
+				"sh": // Shading painting.
+				rulings := path.handlePathOp(op, parentCTM.Mult(gs.CTM))
+				pageText.rulings = append(pageText.rulings, rulings...)
+			case "BMC", "BDC": // Begin marked content sequence.
+				mcStack = append(mcStack, newMarkedContent(op, resources))
+			case "EMC": // End marked content sequence.
+				if len(mcStack) == 0 {
+					common.Log.Debug("EMC without matching BMC or BDC")
+					return nil
 				}
+				mcStack = mcStack[:len(mcStack)-1]
+			case "q":
+				// The text state parameters are part of the graphics state, so they are saved and
+				// restored with it.
+				savedStates = append(savedStates, state)
 			case "Q":
-				if !fontStack.empty() {
-					common.Log.Trace("Restore font state: %s\n->%s\n%s",
-						fontStack.peek(), fontStack.get(-2), fontStack.String())
-					fontStack.pop()
-				}
-				if len(fontStack) >= 2 {
-					common.Log.Trace("Restore font state: %s\n->%s\n%s",
-						state.tfont, fontStack.peek(), fontStack.String())
-					state.tfont = fontStack.pop()
+				if len(savedStates) == 0 {
+					common.Log.Debug("Q without matching q")
+					return nil
 				}
+				saved := savedStates[len(savedStates)-1]
+				savedStates = savedStates[:len(savedStates)-1]
+				// The statistics are totals for the whole content stream, not state parameters.
+				saved.numChars = state.numChars
+				saved.numMisses = state.numMisses
+				saved.fontStats = state.fontStats
+				state = saved
 			case "BT": // Begin text
 				// Begin a text object, initializing the text matrix, Tm, and
 				// the text line matrix, Tlm, to the identity matrix. Text
 				// objects shall not be nested. A second BT shall not appear
 				// before an ET. However, if that happens, all existing marks
 				// are added to the  page marks, in order to avoid losing content.
+				// The same applies to marks from text shown outside a text
+				// object, e.g. between an unmatched ET and this BT.
 				if inTextObj {
 					common.Log.Debug("BT called while in a text object")
-					pageText.marks = append(pageText.marks, to.marks...)
+				} else if len(to.marks) > 0 {
+					common.Log.Debug("Text shown outside a text object")
 				}
+				pageText.marks = append(pageText.marks, to.marks...)
 				inTextObj = true
+				inPhantomObj = false
 
 				graphicsState := gs
 				graphicsState.CTM = parentCTM.Mult(graphicsState.CTM)
-				to = newTextObject(e, resources, graphicsState, &state, &fontStack)
+				to = newTextObject(e, resources, graphicsState, &state)
 			case "ET": // End Text
 				// End text object, discarding text matrix. If the current
 				// text object contains text marks, they are added to the
@@ -134,6 +203,7 @@ func (e *Extractor) extractPageText(contents string, resources *model.PdfPageRes
 					common.Log.Debug("ET called outside of a text object")
 				}
 				inTextObj = false
+				inPhantomObj = false
 				pageText.marks = append(pageText.marks, to.marks...)
 				to.reset()
 			case "T*": // Move to start of next text line
@@ -166,8 +236,9 @@ func (e *Extractor) extractPageText(contents string, resources *model.PdfPageRes
 				}
 				charcodes, ok := core.GetStringBytes(op.Params[0])
 				if !ok {
-					common.Log.Debug("ERROR: Tj op=%s GetStringBytes failed", op)
-					return core.ErrTypeError
+					// Skip malformed operators rather than abandon the page.
+					common.Log.Debug("ERROR: Tj op=%s GetStringBytes failed. Skipping", op)
+					return nil
 				}
 				return to.showText(charcodes)
 			case "TJ": // Show text with adjustable spacing.
@@ -177,8 +248,8 @@ func (e *Extractor) extractPageText(contents string, resources *model.PdfPageRes
 				}
 				args, ok := core.GetArray(op.Params[0])
 				if !ok {
-					common.Log.Debug("ERROR: TJ op=%s GetArrayVal failed", op)
-					return err
+					common.Log.Debug("ERROR: TJ op=%s GetArrayVal failed. Skipping", op)
+					return nil
 				}
 				return to.showTextAdjusted(args)
 			case "'": // Move to next line and show text.
@@ -188,8 +259,8 @@ func (e *Extractor) extractPageText(contents string, resources *model.PdfPageRes
 				}
 				charcodes, ok := core.GetStringBytes(op.Params[0])
 				if !ok {
-					common.Log.Debug("ERROR: ' op=%s GetStringBytes failed", op)
-					return core.ErrTypeError
+					common.Log.Debug("ERROR: ' op=%s GetStringBytes failed. Skipping", op)
+					return nil
 				}
 				to.nextLine()
 				return to.showText(charcodes)
@@ -204,8 +275,8 @@ func (e *Extractor) extractPageText(contents string, resources *model.PdfPageRes
 				}
 				charcodes, ok := core.GetStringBytes(op.Params[2])
 				if !ok {
-					common.Log.Debug("ERROR: \" op=%s GetStringBytes failed", op)
-					return core.ErrTypeError
+					common.Log.Debug("ERROR: \" op=%s GetStringBytes failed. Skipping", op)
+					return nil
 				}
 				to.setCharSpacing(x)
 				to.setWordSpacing(y)
@@ -255,6 +326,17 @@ func (e *Extractor) extractPageText(contents string, resources *model.PdfPageRes
 					return err
 				}
 				to.setTextMatrix(floats)
+			case "gs": // Set graphics state parameters from an ExtGState resource.
+				if ok, _ := to.checkOp(op, 1, false); !ok {
+					common.Log.Debug("ERROR: gs op=%s", op)
+					return nil
+				}
+				name, ok := core.GetNameVal(op.Params[0])
+				if !ok {
+					common.Log.Debug("ERROR: gs op=%s GetNameVal failed", op)
+					return nil
+				}
+				to.setExtGState(name)
 			case "Tr": // Set text rendering mode.
 				if ok, err := to.checkOp(op, 1, true); !ok {
 					common.Log.Debug("ERROR: Tr err=%v", err)
@@ -313,12 +395,26 @@ func (e *Extractor) extractPageText(contents string, resources *model.PdfPageRes
 					return errType
 				}
 
-				_, xtype := resources.GetXObjectByName(*name)
+				xobj, xtype := resources.GetXObjectByName(*name)
+				if xtype == model.XObjectTypeImage {
+					pageText.images = append(pageText.images, ImageRegion{
+						Name: name.String(),
+						BBox: imageBBox(parentCTM.Mult(gs.CTM)),
+					})
+				}
 				if xtype != model.XObjectTypeForm {
 					break
 				}
-				// Only process each form once.
-				formResult, ok := e.formResults[name.String()]
+				// Only process each form once for each position it is drawn at.
+				formCTM := parentCTM.Mult(gs.CTM)
+				key := formKey{xobj: xobj, resources: resources, ctm: formCTM}
+				formResult, ok := e.formResults[key]
+				if !ok && e.formStack[xobj] {
+					// The form is drawn, directly or indirectly, by its own content stream.
+					// Drawing it again would recurse forever so the cycle is broken here.
+					common.Log.Debug("ERROR: Form XObject %s draws itself. Skipping it.", name)
+					break
+				}
 				if !ok {
 					xform, err := resources.GetXObjectFormByName(*name)
 					if err != nil {
@@ -335,19 +431,36 @@ func (e *Extractor) extractPageText(contents string, resources *model.PdfPageRes
 						formResources = resources
 					}
 
+					// The form's matrix maps form space to the user space of the content it is
+					// drawn in.
+					formCTM = formCTM.Mult(formMatrix(xform))
+					if e.formStack == nil {
+						e.formStack = map[*core.PdfObjectStream]bool{}
+					}
+					e.formStack[xobj] = true
 					tList, numChars, numMisses, err := e.extractPageText(string(formContent),
-						formResources, parentCTM.Mult(gs.CTM), level+1)
+						formResources, formCTM, level+1)
+					delete(e.formStack, xobj)
 					if err != nil {
 						common.Log.Debug("ERROR: %v", err)
 						return err
 					}
 					formResult = textResult{*tList, numChars, numMisses}
-					e.formResults[name.String()] = formResult
+					e.formResults[key] = formResult
 				}
 
+				if ok {
+					// Cached form marks weren't counted when they were rendered in this call.
+					if err := e.addMarks(len(formResult.pageText.marks)); err != nil {
+						return err
+					}
+				}
 				pageText.marks = append(pageText.marks, formResult.pageText.marks...)
+				pageText.rulings = append(pageText.rulings, formResult.pageText.rulings...)
+				pageText.images = append(pageText.images, formResult.pageText.images...)
 				state.numChars += formResult.numChars
 				state.numMisses += formResult.numMisses
+				state.fontStats = mergeFontDecodeStats(state.fontStats, formResult.pageText.fontStats)
 			}
 			return nil
 		})
@@ -356,15 +469,52 @@ func (e *Extractor) extractPageText(contents string, resources *model.PdfPageRes
 	if err != nil {
 		common.Log.Debug("ERROR: Processing: err=%v", err)
 	}
+	// Add the marks from a text object with no ET, or from text shown after the last ET.
+	if len(to.marks) > 0 {
+		common.Log.Debug("Text object not closed by ET. inTextObj=%t", inTextObj)
+		pageText.marks = append(pageText.marks, to.marks...)
+	}
+	pageText.marks = replaceActualText(pageText.marks)
+	pageText.fontStats = state.fontStats
 	return pageText, state.numChars, state.numMisses, err
 }
 
+// isShowTextOperand returns true if `operand` is a text showing operator.
+func isShowTextOperand(operand string) bool {
+	switch operand {
+	case "Tj", "TJ", "'", `"`:
+		return true
+	}
+	return false
+}
+
+// formMatrix returns the /Matrix of form XObject `xform`, or the identity matrix if it doesn't
+// have a valid one.
+func formMatrix(xform *model.XObjectForm) transform.Matrix {
+	if arr, ok := core.GetArray(xform.Matrix); ok {
+		if vals, err := core.GetNumbersAsFloat(arr.Elements()); err == nil && len(vals) == 6 {
+			return transform.NewMatrix(vals[0], vals[1], vals[2], vals[3], vals[4], vals[5])
+		}
+		common.Log.Debug("ERROR: Invalid form matrix. Matrix=%s", xform.Matrix)
+	}
+	return transform.IdentityMatrix()
+}
+
 type textResult struct {
 	pageText  PageText
 	numChars  int
 	numMisses int
 }
 
+// formKey is the key of the text of a form XObject in Extractor.formResults. The marks of a form
+// are positioned by the CTM it is drawn with, and forms without resources use the resources of
+// the content stream that draws them, so forms are cached for each of these.
+type formKey struct {
+	xobj      *core.PdfObjectStream
+	resources *model.PdfPageResources
+	ctm       transform.Matrix
+}
+
 //
 // Text operators
 //
@@ -427,6 +577,9 @@ func (to *textObject) showTextAdjusted(args *core.PdfObjectArray) error {
 				return err
 			}
 			dx, dy := -x*0.001*to.state.tfs, 0.0
+			if to.e.options.DetectTJTabs && !vertical && -x*0.001 >= tabJumpEm {
+				to.tabPending = true
+			}
 			if vertical {
 				dy, dx = dx, dy
 			}
@@ -439,10 +592,12 @@ func (to *textObject) showTextAdjusted(args *core.PdfObjectArray) error {
 				common.Log.Trace("showTextAdjusted: Bad string arg. o=%s args=%+v", o, args)
 				return core.ErrTypeError
 			}
-			to.renderText(charcodes)
+			if err := to.renderText(charcodes); err == ErrMaxMarks {
+				return err
+			}
 		default:
-			common.Log.Debug("ERROR: showTextAdjusted. Unexpected type (%T) args=%+v", o, args)
-			return core.ErrTypeError
+			common.Log.Debug("ERROR: showTextAdjusted. Unexpected type (%T) args=%+v. Skipping",
+				o, args)
 		}
 	}
 	return nil
@@ -469,14 +624,10 @@ func (to *textObject) setFont(name string, size float64) error {
 	if to == nil {
 		return nil
 	}
-	font, err := to.getFont(name)
+	font, fontObj, err := to.getFont(name)
 	if err == nil {
 		to.state.tfont = font
-		if len(*to.fontStack) == 0 {
-			to.fontStack.push(font)
-		} else {
-			(*to.fontStack)[len(*to.fontStack)-1] = font
-		}
+		to.state.tfontObj = fontObj
 	} else if err == model.ErrFontNotSupported {
 		// TODO(peterwilliams97): Do we need to handle this case in a special way?
 		return err
@@ -492,7 +643,45 @@ func (to *textObject) setTextRenderMode(mode int) {
 	if to == nil {
 		return
 	}
-	to.state.tmode = RenderMode(mode)
+	if mode < 0 || mode >= len(trRenderModes) {
+		common.Log.Debug("ERROR: Invalid text rendering mode. mode=%d", mode)
+		return
+	}
+	to.state.tmode = trRenderModes[mode]
+}
+
+// trRenderModes maps the operands of the Tr operator to RenderModes. See Table 106 in the PDF
+// 32000 spec.
+var trRenderModes = []RenderMode{
+	RenderModeFill,
+	RenderModeStroke,
+	RenderModeFill | RenderModeStroke,
+	0, // Invisible.
+	RenderModeFill | RenderModeClip,
+	RenderModeStroke | RenderModeClip,
+	RenderModeFill | RenderModeStroke | RenderModeClip,
+	RenderModeClip,
+}
+
+// setExtGState "gs". Set the graphics state parameters that affect text extraction from the
+// ExtGState resource named `name`.
+func (to *textObject) setExtGState(name string) {
+	if to == nil || to.resources == nil {
+		return
+	}
+	obj, ok := to.resources.GetExtGState(core.PdfObjectName(name))
+	if !ok {
+		common.Log.Debug("ERROR: ExtGState not found. name=%#q", name)
+		return
+	}
+	dict, ok := core.GetDict(obj)
+	if !ok {
+		common.Log.Debug("ERROR: ExtGState not a dictionary. name=%#q obj=%T", name, obj)
+		return
+	}
+	if tk, ok := core.GetBool(dict.Get("TK")); ok {
+		to.state.tk = bool(*tk)
+	}
 }
 
 // setTextRise "Ts". Set text rise.
@@ -558,69 +747,6 @@ func (to *textObject) checkOp(op *contentstream.ContentStreamOperation, numParam
 	return true, nil
 }
 
-// fontStacker is the PDF font stack implementation.
-type fontStacker []*model.PdfFont
-
-// String returns a string describing the current state of the font stack.
-func (fontStack *fontStacker) String() string {
-	parts := []string{"---- font stack"}
-	for i, font := range *fontStack {
-		s := "<nil>"
-		if font != nil {
-			s = font.String()
-		}
-		parts = append(parts, fmt.Sprintf("\t%2d: %s", i, s))
-	}
-	return strings.Join(parts, "\n")
-}
-
-// push pushes `font` onto the font stack.
-func (fontStack *fontStacker) push(font *model.PdfFont) {
-	*fontStack = append(*fontStack, font)
-}
-
-// pop pops and returns the element on the top of the font stack if there is one or nil if there isn't.
-func (fontStack *fontStacker) pop() *model.PdfFont {
-	if fontStack.empty() {
-		return nil
-	}
-	font := (*fontStack)[len(*fontStack)-1]
-	*fontStack = (*fontStack)[:len(*fontStack)-1]
-	return font
-}
-
-// peek returns the element on the top of the font stack if there is one or nil if there isn't.
-func (fontStack *fontStacker) peek() *model.PdfFont {
-	if fontStack.empty() {
-		return nil
-	}
-	return (*fontStack)[len(*fontStack)-1]
-}
-
-// get returns the `idx`'th element of the font stack if there is one or nil if there isn't.
-//  idx = 0: bottom of font stack
-//  idx = len(fontstack) - 1: top of font stack
-//  idx = -n is same as dx = len(fontstack) - n, so fontstack.get(-1) is same as fontstack.peek()
-func (fontStack *fontStacker) get(idx int) *model.PdfFont {
-	if idx < 0 {
-		idx += fontStack.size()
-	}
-	if idx < 0 || idx > fontStack.size()-1 {
-		return nil
-	}
-	return (*fontStack)[idx]
-}
-
-// empty returns true if the font stack is empty.
-func (fontStack *fontStacker) empty() bool {
-	return len(*fontStack) == 0
-}
-
-// size returns the number of elements in the font stack.
-func (fontStack *fontStacker) size() int {
-	return len(*fontStack)
-}
-
 // 9.3 Text State Parameters and Operators (page 243)
 // Some of these parameters are expressed in unscaled text space units. This means that they shall
 // be specified in a coordinate system that shall be defined by the text matrix, Tm but shall not be
@@ -634,11 +760,15 @@ type textState struct {
 	tl    float64        // Leading. Unscaled text space units. Used by TD,T*,'," see Table 108.
 	tfs   float64        // Text font size.
 	tmode RenderMode     // Text rendering mode.
+	tk    bool           // Text knockout. Set by the /TK entry of ExtGState resources.
 	trise float64        // Text rise. Unscaled text space units. Set by Ts.
 	tfont *model.PdfFont // Text font.
+	// tfontObj is the object that tfont was loaded from. It identifies the font across pages.
+	tfontObj core.PdfObject
 	// For debugging
 	numChars  int
 	numMisses int
+	fontStats []FontDecodeStats // Per font breakdown of numChars and numMisses.
 }
 
 // 9.4.1 General (page 248)
@@ -661,11 +791,24 @@ type textObject struct {
 	e         *Extractor
 	resources *model.PdfPageResources
 	gs        contentstream.GraphicsState
-	fontStack *fontStacker
 	state     *textState
 	tm        transform.Matrix // Text matrix. For the character pointer.
 	tlm       transform.Matrix // Text line matrix. For the start of line pointer.
 	marks     []textMark       // Text marks get written here.
+
+	// mcid is the marked content identifier of the marked content sequence that the text object's
+	// text is in, or -1 if it isn't in one with an MCID.
+	mcid int
+	// lang is the language of the marked content sequence that the text object's text is in, or
+	// "" if it isn't in one that specifies a language.
+	lang string
+	// actualText is the replacement text span that the text object's text is in, or nil if it
+	// isn't in one.
+	actualText *actualTextSpan
+
+	// tabPending is set when a TJ adjustment moves the text position by a tab jump. The next text
+	// mark is marked as following a tab.
+	tabPending bool
 }
 
 // newTextState returns a default textState.
@@ -673,20 +816,21 @@ func newTextState() textState {
 	return textState{
 		th:    100,
 		tmode: RenderModeFill,
+		tk:    true,
 	}
 }
 
 // newTextObject returns a default textObject.
 func newTextObject(e *Extractor, resources *model.PdfPageResources, gs contentstream.GraphicsState,
-	state *textState, fontStack *fontStacker) *textObject {
+	state *textState) *textObject {
 	return &textObject{
 		e:         e,
 		resources: resources,
 		gs:        gs,
-		fontStack: fontStack,
 		state:     state,
 		tm:        transform.IdentityMatrix(),
 		tlm:       transform.IdentityMatrix(),
+		mcid:      -1,
 	}
 }
 
@@ -700,15 +844,30 @@ func (to *textObject) reset() {
 
 // renderText processes and renders byte array `data` for extraction purposes.
 func (to *textObject) renderText(data []byte) error {
-	font := to.getCurrentFont()
+	font, isDefault := to.getCurrentFont()
 	charcodes := font.BytesToCharcodes(data)
-	texts, numChars, numMisses := font.CharcodesToStrings(charcodes)
+	override := to.e.cmapOverride(font)
+	if override != nil {
+		charcodes = override.charcodes(data, charcodes)
+	}
+	texts, decodings, numChars, numMisses := font.CharcodesToStringsDecodings(charcodes)
+	if override != nil {
+		numMisses -= override.decode(charcodes, texts, decodings)
+	}
+	numMisses -= decodeTabs(font, charcodes, texts, decodings)
 	if numMisses > 0 {
 		common.Log.Debug("renderText: numChars=%d numMisses=%d", numChars, numMisses)
 	}
 
 	to.state.numChars += numChars
 	to.state.numMisses += numMisses
+	to.state.fontStats = addFontDecodeStats(to.state.fontStats, FontDecodeStats{
+		Font:      font,
+		NumChars:  numChars,
+		NumMisses: numMisses,
+		fontObj:   to.state.tfontObj,
+		isDefault: isDefault,
+	})
 
 	state := to.state
 	tfs := state.tfs
@@ -727,12 +886,19 @@ func (to *textObject) renderText(data []byte) error {
 		tfs*th, 0,
 		0, tfs,
 		0, state.trise)
+	// baseMatrix is stateMatrix without the text rise. It is used to find the baseline of
+	// superscripts and subscripts.
+	baseMatrix := transform.NewMatrix(
+		tfs*th, 0,
+		0, tfs,
+		0, 0)
+	script := scriptPosition(state)
 
 	common.Log.Trace("renderText: %d codes=%+v runes=%q", len(charcodes), charcodes, len(texts))
 
 	for i, text := range texts {
 		r := []rune(text)
-		if len(r) == 1 && r[0] == '\x00' {
+		if len(r) == 1 && r[0] == '\x00' && !to.e.options.KeepControlChars {
 			continue
 		}
 
@@ -744,16 +910,31 @@ func (to *textObject) renderText(data []byte) error {
 		// calculate the text location displacement due to writing `r`. We will use this to update
 		// to.tm
 
-		// w is the unscaled movement at the end of a word.
+		// w is the unscaled movement at the end of a word. Word spacing is applied to the single
+		// byte character code 32 but not to multi-byte codes, whatever they decode to.
 		w := 0.0
-		if string(r) == " " {
+		if code == 32 && font.IsSingleByteCode(code) {
 			w = state.tw
 		}
 
-		m, ok := font.GetCharMetrics(code)
+		metricCode := code
+		if override != nil {
+			if cid, ok := override.cid(code); ok {
+				metricCode = cid
+			}
+		}
+		m, ok := font.GetCharMetrics(metricCode)
 		if !ok {
-			common.Log.Debug("ERROR: No metric for code=%d r=0x%04x=%+q %s", code, r, r, font)
-			return fmt.Errorf("no char metrics: font=%s code=%d", font.String(), code)
+			// One bad glyph shouldn't stop the extraction of the page so we estimate its width
+			// and count it as a metric miss.
+			m = estimateCharMetrics(font, r, spaceMetrics)
+			common.Log.Debug("WARNING: No metric for code=%d r=0x%04x=%+q %s. Using Wx=%g",
+				code, r, r, font, m.Wx)
+			to.state.fontStats = addFontDecodeStats(to.state.fontStats, FontDecodeStats{
+				Font:            font,
+				NumMetricMisses: 1,
+				fontObj:         to.state.tfontObj,
+			})
 		}
 
 		// c is the character size in unscaled text units.
@@ -769,6 +950,21 @@ func (to *textObject) renderText(data []byte) error {
 		td0 := translationMatrix(t0)
 		td := translationMatrix(t)
 
+		if state.tmode.Invisible() && to.e.options.ExcludeInvisibleText {
+			to.tm.Concat(td)
+			continue
+		}
+		if trm.Singular() {
+			// The text is collapsed onto a line or a point so it has no meaningful position or
+			// size. Marks like this break the gap calculations that words are built from.
+			// Text shown before a font is set has no font size so it is skipped too. It is still
+			// counted by DefaultFontMarks.
+			common.Log.Debug("WARNING: Singular text rendering matrix. Skipping %q trm=%s",
+				text, trm)
+			to.tm.Concat(td)
+			continue
+		}
+
 		common.Log.Trace("\"%c\" stateMatrix=%s CTM=%s Tm=%s", r, stateMatrix, to.gs.CTM, to.tm)
 		common.Log.Trace("tfs=%.3f th=%.3f Tc=%.3f w=%.3f (Tw=%.3f)", tfs, th, state.tc, w, state.tw)
 		common.Log.Trace("m=%s c=%+v t0=%+v td0=%s trm0=%s", m, c, t0, td0, td0.Mult(to.tm).Mult(to.gs.CTM))
@@ -790,8 +986,28 @@ func (to *textObject) renderText(data []byte) error {
 				mark.original = string(original)
 			}
 		}
+		mark.confidence = decodeConfidences[decodings[i]]
 		common.Log.Trace("i=%d code=%d mark=%s trm=%s", i, code, mark, trm)
-		to.marks = append(to.marks, mark)
+		marks := []textMark{mark}
+		if to.e.options.SplitLigatures {
+			marks = to.splitTextMark(mark)
+		}
+		if script != ScriptNone {
+			start, base := translation(trm), translation(to.gs.CTM.Mult(to.tm).Mult(baseMatrix))
+			rise := transform.Point{X: start.X - base.X, Y: start.Y - base.Y}
+			for k := range marks {
+				marks[k].script = script
+				if to.e.options.UnicodeScripts {
+					marks[k].toUnicodeScript(rise)
+				}
+			}
+		}
+		marks[0].tabBefore = to.tabPending
+		to.tabPending = false
+		if err := to.e.addMarks(len(marks)); err != nil {
+			return err
+		}
+		to.marks = append(to.marks, marks...)
 
 		// update the text matrix by the displacement of the text location.
 		to.tm.Concat(td)
@@ -801,6 +1017,60 @@ func (to *textObject) renderText(data []byte) error {
 	return nil
 }
 
+// estimateCharMetrics returns estimated metrics for rune(s) `r` in `font` for when font has no
+// metrics for them. The estimate is the first of the following that is available
+//  - the font descriptor's /AvgWidth,
+//  - the font descriptor's /MissingWidth,
+//  - the width of `r` in the default font,
+//  - `spaceMetrics`, the width of a space in `font`.
+func estimateCharMetrics(font *model.PdfFont, r []rune, spaceMetrics model.CharMetrics) model.CharMetrics {
+	if font != nil {
+		if desc, _ := font.GetFontDescriptor(); desc != nil {
+			for _, obj := range []core.PdfObject{desc.AvgWidth, desc.MissingWidth} {
+				if w, err := core.GetNumberAsFloat(obj); err == nil && w > 0 {
+					return model.CharMetrics{Wx: w}
+				}
+			}
+		}
+	}
+	if len(r) == 1 {
+		if m, ok := model.DefaultFont().GetRuneMetrics(r[0]); ok && m.Wx > 0 {
+			return m
+		}
+	}
+	return spaceMetrics
+}
+
+// splitTextMark returns `tm` split into one textMark per rune of its text. The runes divide the
+// advance of `tm` equally. The Original text of `tm` is kept on the first of the returned marks.
+func (to *textObject) splitTextMark(tm textMark) []textMark {
+	runes := []rune(tm.text)
+	if len(runes) <= 1 {
+		return []textMark{tm}
+	}
+	start := translation(tm.trm)
+	n := float64(len(runes))
+	point := func(k int) transform.Point {
+		f := float64(k) / n
+		return transform.Point{
+			X: start.X + f*(tm.end.X-start.X),
+			Y: start.Y + f*(tm.end.Y-start.Y),
+		}
+	}
+	marks := make([]textMark, len(runes))
+	for k, r := range runes {
+		p0 := point(k)
+		trm := translationMatrix(transform.Point{X: p0.X - start.X, Y: p0.Y - start.Y}).Mult(tm.trm)
+		marks[k] = to.newTextMark(string(r), trm, point(k+1), tm.spaceWidth, tm.font, tm.charspacing)
+	}
+	marks[0].original = tm.original
+	return marks
+}
+
+// tabJumpEm is the minimum TJ adjustment, in ems, that is treated as a tab jump when
+// ExtractOptions.DetectTJTabs is set. Kerning and word spacing adjustments are much smaller.
+const tabJumpEm = 2.0
+
 // glyphTextRatio converts Glyph metrics units to unscaled text space units.
 const glyphTextRatio = 1.0 / 1000.0
 
@@ -837,10 +1107,20 @@ type textMark struct {
 	spaceWidth    float64            // Best guess at the width of a space in the font the text was rendered with.
 	font          *model.PdfFont     // The font the mark was drawn with.
 	fontsize      float64            // The font size the mark was drawn with.
+	fillColor     color.Color        // The fill color the mark was drawn with.
+	renderMode    RenderMode         // The text rendering mode the mark was drawn with.
+	confidence    DecodeConfidence   // How reliably the text was decoded.
+	overlapping   bool               // Drawn with text knockout off so overlaps are intentional.
+	tabBefore     bool               // Preceded by a tab jump in a TJ array.
+	mcid          int                // Marked content identifier. -1 if none.
+	lang          string             // Language from the marked content /Lang. "" if none.
+	script        ScriptPosition     // Whether the mark was drawn as a superscript or subscript.
+	actualText    *actualTextSpan    // Replacement text span the mark was drawn in. nil if none.
 	charspacing   float64            // TODO (peterwilliams97: Should this be exposed in TextMark?
 	trm           transform.Matrix   // The current text rendering matrix (TRM above).
 	end           transform.Point    // The end of character device coordinates.
 	count         int64              // To help with reading debug logs.
+	serial        int                // Index of the mark in the order the page drew its marks.
 }
 
 // newTextMark returns a textMark for text `text` rendered with text rendering matrix (TRM) `trm`
@@ -857,6 +1137,12 @@ func (to *textObject) newTextMark(text string, trm transform.Matrix, end transfo
 	} else {
 		height = trm.ScalingFactorX()
 	}
+	// Mirrored text is drawn on the other side of its baseline from unmirrored text with the same
+	// orientation.
+	mirrored := isMirrored(trm)
+	if mirrored {
+		height = -height
+	}
 
 	start := translation(trm)
 	bbox := model.PdfRectangle{Llx: start.X, Lly: start.Y, Urx: end.X, Ury: end.Y}
@@ -870,16 +1156,36 @@ func (to *textObject) newTextMark(text string, trm transform.Matrix, end transfo
 	default:
 		bbox.Ury += height
 	}
+	orientedStart := start.Rotate(theta)
+	orientedEnd := end.Rotate(theta)
+	if mirrored {
+		// Flip the y axis so that lines of mirrored text are ordered from top to bottom when
+		// sorting by descending orientedStart.Y, as they are for unmirrored text.
+		orientedStart.Y = -orientedStart.Y
+		orientedEnd.Y = -orientedEnd.Y
+		bbox = model.PdfRectangle{
+			Llx: math.Min(bbox.Llx, bbox.Urx),
+			Lly: math.Min(bbox.Lly, bbox.Ury),
+			Urx: math.Max(bbox.Llx, bbox.Urx),
+			Ury: math.Max(bbox.Lly, bbox.Ury),
+		}
+	}
 	tm := textMark{
 		text:          text,
 		orient:        orient,
 		bbox:          bbox,
-		orientedStart: start.Rotate(theta),
-		orientedEnd:   end.Rotate(theta),
+		orientedStart: orientedStart,
+		orientedEnd:   orientedEnd,
 		height:        math.Abs(height),
 		spaceWidth:    spaceWidth,
 		font:          font,
 		fontsize:      to.state.tfs,
+		fillColor:     to.getFillColor(),
+		renderMode:    to.state.tmode,
+		overlapping:   !to.state.tk,
+		mcid:          to.mcid,
+		lang:          to.lang,
+		actualText:    to.actualText,
 		charspacing:   charspacing,
 		trm:           trm,
 		end:           end,
@@ -891,6 +1197,13 @@ func (to *textObject) newTextMark(text string, trm transform.Matrix, end transfo
 	return tm
 }
 
+// isMirrored returns true if `m` mirrors the text it renders, i.e. if it has a negative
+// determinant. Mirrored text is read in the opposite direction on the page to unmirrored text with
+// the same orientation.
+func isMirrored(m transform.Matrix) bool {
+	return m[0]*m[4]-m[1]*m[3] < 0
+}
+
 // isTextSpace returns true if `text` contains nothing but space code points.
 func isTextSpace(text string) bool {
 	for _, r := range text {
@@ -901,6 +1214,35 @@ func isTextSpace(text string) bool {
 	return true
 }
 
+// decodeTabs sets the texts of the single byte tab character codes (9) in `charcodes` that `font`
+// couldn't decode to "\t". PDFs made from plain text may show the tabs in the text and the
+// encodings of most simple fonts have no glyph for them. It returns the number of codes that it
+// decoded.
+func decodeTabs(font *model.PdfFont, charcodes []textencoding.CharCode, texts []string,
+	decodings []model.CharcodeDecoding) int {
+	decoded := 0
+	for i, code := range charcodes {
+		if code == '\t' && decodings[i] == model.CharcodeDecodingMissing &&
+			font.IsSingleByteCode(code) {
+			texts[i] = "\t"
+			decodings[i] = model.CharcodeDecodingDefault
+			decoded++
+		}
+	}
+	return decoded
+}
+
+// isControlText returns true if `text` is not empty and consists of control characters other than
+// white space.
+func isControlText(text string) bool {
+	for _, r := range text {
+		if !unicode.IsControl(r) || unicode.IsSpace(r) {
+			return false
+		}
+	}
+	return text != ""
+}
+
 // nearestMultiple return the integer multiple of `m` that is closest to `x`.
 func nearestMultiple(x float64, m int) int {
 	if m == 0 {
@@ -925,19 +1267,51 @@ func (tm textMark) Width() float64 {
 // ToTextMark returns the public view of `tm`.
 func (tm textMark) ToTextMark() TextMark {
 	return TextMark{
-		Text:     tm.text,
-		Original: tm.original,
-		BBox:     tm.bbox,
-		Font:     tm.font,
-		FontSize: tm.fontsize,
+		Text:              tm.text,
+		Original:          tm.original,
+		BBox:              tm.bbox,
+		Font:              tm.font,
+		FontSize:          tm.fontsize,
+		EffectiveFontSize: tm.height,
+		FillColor:         tm.fillColor,
+		RenderMode:        tm.renderMode,
+		DecodeConfidence:  tm.confidence,
+		Control:           isControlText(tm.text),
+		Lang:              tm.lang,
+		Script:            tm.script,
+
+		overlapping: tm.overlapping,
+		mcid:        tm.mcid,
 	}
 }
 
 // PageText represents the layout of text on a device page.
 type PageText struct {
-	marks     []textMark // Texts and their positions on a PDF page.
-	viewText  string     // Extracted page text.
-	viewMarks []TextMark // Public view of `marks`.
+	marks          []textMark     // Texts and their positions on a PDF page.
+	viewText       string         // Extracted page text.
+	viewMarks      []TextMark     // Public view of `marks`.
+	viewLineStarts []int          // Indexes of the first marks of the lines in `viewMarks`.
+	options        ExtractOptions // Options used to compute the views.
+	rotation       int            // The page's /Rotate value in degrees.
+	rulings        []Ruling       // Horizontal and vertical lines drawn on the page.
+	// mediaBox is the page's MediaBox in the coordinates of the text. It is the zero rectangle if
+	// the page size is unknown.
+	mediaBox model.PdfRectangle
+	// fontStats are the character decoding statistics of the fonts used.
+	fontStats []FontDecodeStats
+	// images are the placements of the image XObjects drawn on the page.
+	images []ImageRegion
+	// index is the spatial index of `marks` used by ApplyArea. It is shared by copies of the
+	// PageText.
+	index *markIndex
+}
+
+// Rotation returns the rotation in degrees, clockwise, that the page is displayed with. It is the
+// page's /Rotate value normalized to 0, 90, 180 or 270. The extractor doesn't apply this rotation:
+// text positions are in the unrotated page space, so callers placing overlays on the displayed
+// page need to rotate them by this angle.
+func (pt PageText) Rotation() int {
+	return pt.rotation
 }
 
 // String returns a string describing `pt`.
@@ -951,6 +1325,20 @@ func (pt PageText) String() string {
 	return strings.Join(parts, "\n")
 }
 
+// DefaultFontMarks returns the number of characters on the page that were shown with the default
+// font because no font had been set by a Tf operator when they were shown. These characters have
+// no font size, so they are not included in the marks. A high count indicates a content stream
+// that shows text before setting a font.
+func (pt PageText) DefaultFontMarks() int {
+	n := 0
+	for _, s := range pt.fontStats {
+		if s.isDefault {
+			n += s.NumChars
+		}
+	}
+	return n
+}
+
 // length returns the number of elements in `pt.marks`.
 func (pt PageText) length() int {
 	return len(pt.marks)
@@ -961,6 +1349,21 @@ func (pt PageText) Text() string {
 	return pt.viewText
 }
 
+// RawText returns the decoded text of the marks in `pt` in the order the page's content streams
+// drew them, with no word, line or reading order processing. Comparing it with Text() shows
+// whether wrong text is caused by decoding, which makes RawText() wrong, or by layout, which
+// leaves RawText() right.
+func (pt PageText) RawText() string {
+	marks := make([]textMark, len(pt.marks))
+	copy(marks, pt.marks)
+	sort.SliceStable(marks, func(i, j int) bool { return marks[i].serial < marks[j].serial })
+	var b strings.Builder
+	for _, tm := range marks {
+		b.WriteString(tm.text)
+	}
+	return b.String()
+}
+
 // ToText returns the page text as a single string.
 // Deprecated: This function is deprecated and will be removed in a future major version. Please use
 // Text() instead.
@@ -968,6 +1371,56 @@ func (pt PageText) ToText() string {
 	return pt.Text()
 }
 
+// TextWithOrder returns the page text with its marks in the order given by `less` instead of the
+// extractor's reading order. `less` reports whether mark `a` comes before mark `b`. It is called
+// with the non-Meta marks of the page and the sort is stable, so marks that `less` doesn't order
+// stay in reading order. This is for pages whose layouts defeat the reading order heuristics when
+// the caller knows the correct order. e.g. Strictly top to bottom order is
+//     pt.TextWithOrder(func(a, b TextMark) bool { return a.BBox.Ury > b.BBox.Ury })
+// Marks that are on different lines in the reading order are separated by the line separator.
+// Marks on the same line are separated as they are in the reading order if they are adjacent
+// there, and by a space otherwise.
+func (pt PageText) TextWithOrder(less func(a, b TextMark) bool) string {
+	// orderedMark is a mark with its position in the reading order.
+	type orderedMark struct {
+		tm    TextMark
+		line  int    // Index of the mark's line.
+		index int    // Index of the mark in the non-Meta marks.
+		sep   string // Separator before the mark in its line.
+	}
+	var marks []orderedMark
+	for i, line := range pt.viewLines() {
+		sep := ""
+		for _, tm := range line {
+			if tm.Meta {
+				sep += tm.Text
+				continue
+			}
+			marks = append(marks, orderedMark{tm: tm, line: i, index: len(marks), sep: sep})
+			sep = ""
+		}
+	}
+	sort.SliceStable(marks, func(i, j int) bool { return less(marks[i].tm, marks[j].tm) })
+
+	lineSep := pt.options.lineSeparator()
+	var sb strings.Builder
+	for i, m := range marks {
+		if i > 0 {
+			prev := marks[i-1]
+			switch {
+			case m.line != prev.line:
+				sb.WriteString(lineSep)
+			case m.index == prev.index+1:
+				sb.WriteString(m.sep)
+			default:
+				sb.WriteString(spaceMark.Text)
+			}
+		}
+		sb.WriteString(m.tm.Text)
+	}
+	return sb.String()
+}
+
 // Marks returns the TextMark collection for a page. It represents all the text on the page.
 func (pt PageText) Marks() *TextMarkArray {
 	return &TextMarkArray{marks: pt.viewMarks}
@@ -1033,7 +1486,7 @@ func (ma *TextMarkArray) RangeOffset(start, end int) (*TextMarkArray, error) {
 		return nil, err
 	}
 	iEnd := sort.Search(n, func(i int) bool { return ma.marks[i].Offset > end-1 })
-	if !(0 <= iEnd && iEnd < n) {
+	if !(0 <= iEnd && iEnd <= n) {
 		err := fmt.Errorf("Out of range. end=%d iEnd=%d len=%d\n\tfirst=%v\n\t last=%v",
 			end, iEnd, n, ma.marks[0], ma.marks[n-1])
 		return nil, err
@@ -1060,6 +1513,87 @@ func (ma *TextMarkArray) BBox() (model.PdfRectangle, bool) {
 	return bbox, true
 }
 
+// BBoxForRange returns the smallest axis-aligned rectangle that encloses the text in the substring
+// Text()[start:end] of `pt`. Spaces and line breaks are not included. It returns false if there is
+// no non-space text in the range.
+// Use BBoxesForRange to get one rectangle per line for ranges that span several lines.
+func (pt PageText) BBoxForRange(start, end int) (model.PdfRectangle, bool) {
+	return marksBBox(rangeMarks(pt.viewMarks, start, end))
+}
+
+// BBoxesForRange returns the bounding boxes of the text in the substring Text()[start:end] of `pt`,
+// one for each line of text that the substring spans, in the order of the lines in Text(). Lines
+// that have no non-space text in the range are skipped. This is what is needed to highlight a
+// range of text that spans several lines.
+func (pt PageText) BBoxesForRange(start, end int) []model.PdfRectangle {
+	var bboxes []model.PdfRectangle
+	for _, line := range pt.viewLines() {
+		if bbox, ok := marksBBox(rangeMarks(line, start, end)); ok {
+			bboxes = append(bboxes, bbox)
+		}
+	}
+	return bboxes
+}
+
+// rangeMarks returns the marks in `marks`, which are in offset order, that have
+// `start` <= TextMark.Offset < `end`.
+func rangeMarks(marks []TextMark, start, end int) []TextMark {
+	i0 := sort.Search(len(marks), func(i int) bool { return marks[i].Offset >= start })
+	i1 := sort.Search(len(marks), func(i int) bool { return marks[i].Offset >= end })
+	if i1 <= i0 {
+		return nil
+	}
+	return marks[i0:i1]
+}
+
+// marksBBox returns the smallest axis-aligned rectangle that encloses the non-space, non-Meta
+// marks in `marks`. It returns false if there are no such marks.
+func marksBBox(marks []TextMark) (model.PdfRectangle, bool) {
+	var bbox model.PdfRectangle
+	found := false
+	for _, tm := range marks {
+		if tm.Meta || isTextSpace(tm.Text) {
+			continue
+		}
+		if !found {
+			bbox = tm.BBox
+			found = true
+			continue
+		}
+		bbox = rectUnion(bbox, tm.BBox)
+	}
+	return bbox, found
+}
+
+// DominantTextColor returns the fill color that covers the largest area of the non-space
+// TextMarks in `ma`. It returns nil if there are no such TextMarks.
+func (ma *TextMarkArray) DominantTextColor() color.Color {
+	areas := map[color.RGBA]float64{}
+	var best color.RGBA
+	bestArea := -1.0
+	for _, tm := range ma.Elements() {
+		if tm.Meta || tm.FillColor == nil || isTextSpace(tm.Text) {
+			continue
+		}
+		c := color.RGBAModel.Convert(tm.FillColor).(color.RGBA)
+		b := tm.BBox
+		areas[c] += math.Abs((b.Urx - b.Llx) * (b.Ury - b.Lly))
+		if areas[c] > bestArea {
+			best, bestArea = c, areas[c]
+		}
+	}
+	if bestArea < 0 {
+		return nil
+	}
+	return best
+}
+
+// DominantTextColor returns the fill color that covers the largest area of the text on the page.
+// It returns nil if there is no text on the page.
+func (pt PageText) DominantTextColor() color.Color {
+	return pt.Marks().DominantTextColor()
+}
+
 // rectUnion returns the smallest axis-aligned rectangle that contains `b1` and `b2`.
 func rectUnion(b1, b2 model.PdfRectangle) model.PdfRectangle {
 	return model.PdfRectangle{
@@ -1070,6 +1604,17 @@ func rectUnion(b1, b2 model.PdfRectangle) model.PdfRectangle {
 	}
 }
 
+// transformRect returns the smallest axis-aligned rectangle that contains `r` transformed by `m`.
+func transformRect(m transform.Matrix, r model.PdfRectangle) model.PdfRectangle {
+	t := model.PdfRectangle{Llx: math.Inf(1), Lly: math.Inf(1), Urx: math.Inf(-1), Ury: math.Inf(-1)}
+	for _, p := range [][2]float64{{r.Llx, r.Lly}, {r.Urx, r.Lly}, {r.Llx, r.Ury}, {r.Urx, r.Ury}} {
+		q := translation(m.Mult(translationMatrix(transform.Point{X: p[0], Y: p[1]})))
+		t.Llx, t.Urx = math.Min(t.Llx, q.X), math.Max(t.Urx, q.X)
+		t.Lly, t.Ury = math.Min(t.Lly, q.Y), math.Max(t.Ury, q.Y)
+	}
+	return t
+}
+
 // TextMark represents extracted text on a page with information regarding both textual content,
 // formatting (font and size) and positioning.
 // It is the smallest unit of text on a PDF page, typically a single character.
@@ -1100,8 +1645,48 @@ type TextMark struct {
 	BBox model.PdfRectangle
 	// Font is the font the text was drawn with.
 	Font *model.PdfFont
-	// FontSize is the font size the text was drawn with.
+	// FontSize is the font size the text was drawn with. This is the size set by the Tf operator,
+	// which is scaled by the text and current transformation matrices.
 	FontSize float64
+	// EffectiveFontSize is the size in points that the text was drawn with on the page. It is
+	// FontSize scaled by the text and current transformation matrices, so it is the size to use
+	// for checks such as minimum type sizes. e.g. Text drawn with Tf size 1 and a text matrix
+	// that scales by 10 has a FontSize of 1 and an EffectiveFontSize of 10.
+	EffectiveFontSize float64
+	// FillColor is the fill color the text was drawn with.
+	FillColor color.Color
+	// RenderMode is the text rendering mode the text was drawn with. It tells whether the glyphs
+	// were filled, stroked (outlined), both or neither. e.g. Decorative titles are often stroked
+	// but not filled.
+	RenderMode RenderMode
+	// DecodeConfidence tells how reliably Text was decoded from the character codes in the PDF. It
+	// is not set for Meta marks.
+	DecodeConfidence DecodeConfidence
+	// Control is true if Text consists of control characters other than white space, such as NUL
+	// or BEL. These usually indicate encoding problems. NULs are only extracted if
+	// ExtractOptions.KeepControlChars is set.
+	Control bool
+	// Lang is the language of the text, e.g. "en-US" or "de", from the /Lang entry of the marked
+	// content sequence the text was drawn in. It is "" if the text is not in a marked content
+	// sequence that specifies a language. The document's default language is in the /Lang entry
+	// of its catalog.
+	Lang string
+	// Script tells whether the text was drawn as a superscript or subscript by raising or
+	// lowering it with the text rise (Ts).
+	Script ScriptPosition
+
+	// Gap is the distance from the end of the text to the start of the next text on the same line,
+	// measured along the line. It is 0 for the last text on a line and for Meta marks. Abnormally
+	// wide gaps between the letters of a word, e.g. "c o n f i d e n t i a l" drawn with wide
+	// character spacing instead of spaces, can be detected by comparing Gap to FontSize.
+	Gap float64
+
+	// overlapping is true for text drawn with text knockout off. Overlaps between such marks are
+	// intentional, e.g. drop shadows, so they are not removed as duplicates.
+	overlapping bool
+	// mcid is the marked content identifier of the text. It links the text to the document's
+	// structure tree. It is -1 if the text has no MCID. It is not set for Meta marks.
+	mcid int
 	// Offset is the offset of the start of TextMark.Text in the extracted text. If you do this
 	//   text, textMarks := pageText.Text(), pageText.Marks()
 	//   marks := textMarks.Elements()
@@ -1140,19 +1725,19 @@ func (pt *PageText) computeViews() {
 	// We sort with a y tolerance to allow for subscripts, diacritics etc.
 	tol := minFloat(fontHeight*0.19, 5.0)
 	common.Log.Trace("ToTextLocation: %d elements fontHeight=%.1f tol=%.1f", len(pt.marks), fontHeight, tol)
-	// Uncomment the 2 following Debug statements to see the effects of sorting.
-	// common.Log.Debug("computeViews: Before sorting %s", pt)
-	pt.sortPosition(tol)
-	// common.Log.Debug("computeViews: After sorting %s", pt)
-	lines := pt.toLines(tol)
+	pt.attachDropCaps()
+	lines := pt.layoutLines(tol)
+	lineSep := pt.options.lineSeparator()
 	texts := make([]string, len(lines))
 	for i, l := range lines {
 		texts[i] = strings.Join(l.words(), wordJoiner)
 	}
-	text := strings.Join(texts, lineJoiner)
+	text := strings.Join(texts, lineSep)
 	var marks []TextMark
+	var lineStarts []int
 	offset := 0
 	for i, l := range lines {
+		lineStarts = append(lineStarts, len(marks))
 		for j, tm := range l.marks {
 			tm.Offset = offset
 			marks = append(marks, tm)
@@ -1173,18 +1758,74 @@ func (pt *PageText) computeViews() {
 		if i == len(lines)-1 {
 			break
 		}
-		if lineJoinerLen > 0 {
-			tm := TextMark{
-				Offset: offset,
-				Text:   lineJoiner,
-				Meta:   true,
-			}
-			marks = append(marks, tm)
-			offset += lineJoinerLen
+		tm := TextMark{
+			Offset: offset,
+			Text:   lineSep,
+			Meta:   true,
 		}
+		marks = append(marks, tm)
+		offset += len(lineSep)
 	}
 	pt.viewText = text
 	pt.viewMarks = marks
+	pt.viewLineStarts = lineStarts
+}
+
+// layoutLines sorts `pt.marks` in the reading order of pt.options.ReadingOrder and returns them
+// as lines of text. `tol` is the y tolerance for marks in the same line. Pages with more than
+// pt.options.TileMarks marks are laid out one tile at a time.
+func (pt *PageText) layoutLines(tol float64) []textLine {
+	if n := pt.options.TileMarks; n > 0 && len(pt.marks) > n {
+		var lines []textLine
+		marks := make([]textMark, 0, len(pt.marks))
+		for _, tile := range tileMarks(pt.marks, n) {
+			block := PageText{marks: tile, options: pt.options}
+			block.options.TileMarks = 0
+			lines = append(lines, block.layoutLines(tol)...)
+			marks = append(marks, block.marks...)
+		}
+		pt.marks = marks
+		return lines
+	}
+	if pt.options.ReadingOrder != ReadingOrderXYCut {
+		// Uncomment the 2 following Debug statements to see the effects of sorting.
+		// common.Log.Debug("computeViews: Before sorting %s", pt)
+		pt.sortPosition(tol)
+		// common.Log.Debug("computeViews: After sorting %s", pt)
+		return pt.toLines(tol)
+	}
+	var lines []textLine
+	var marks []textMark
+	for _, blockMarks := range xyCut(pt.marks) {
+		block := PageText{marks: blockMarks, options: pt.options}
+		block.sortPosition(tol)
+		lines = append(lines, block.toLines(tol)...)
+		marks = append(marks, block.marks...)
+	}
+	pt.marks = marks
+	return lines
+}
+
+// viewLines returns `pt.viewMarks` split into the lines of text that computeViews created. The
+// line separator marks are not included in the returned lines.
+func (pt PageText) viewLines() [][]TextMark {
+	n := len(pt.viewMarks)
+	var lines [][]TextMark
+	for i, start := range pt.viewLineStarts {
+		if start >= n {
+			break
+		}
+		end := n
+		if i+1 < len(pt.viewLineStarts) {
+			// Lines are followed by a line separator mark.
+			end = pt.viewLineStarts[i+1] - 1
+		}
+		if end > n {
+			end = n
+		}
+		lines = append(lines, pt.viewMarks[start:end])
+	}
+	return lines
 }
 
 // height returns the max height of the elements in `pt.marks`.
@@ -1198,22 +1839,57 @@ func (pt PageText) height() float64 {
 	return fontHeight
 }
 
+// BaselineGrid returns the y coordinates of the baselines of the upright text on the page, sorted
+// from the top of the page to the bottom. Baselines that are within a small tolerance of each
+// other are clustered and the returned values are the mean baselines of the clusters.
+func (pt PageText) BaselineGrid() []float64 {
+	var ys []float64
+	for _, tm := range pt.marks {
+		if tm.orient != 0 || isTextSpace(tm.text) {
+			continue
+		}
+		ys = append(ys, tm.orientedStart.Y)
+	}
+	if len(ys) == 0 {
+		return nil
+	}
+	sort.Sort(sort.Reverse(sort.Float64Slice(ys)))
+	// We cluster with the same y tolerance that computeViews uses to group marks into lines.
+	tol := minFloat(pt.height()*0.19, 5.0)
+	var grid []float64
+	sum, n := ys[0], 1
+	for i := 1; i < len(ys); i++ {
+		if ys[i-1]-ys[i] > tol {
+			grid = append(grid, sum/float64(n))
+			sum, n = 0, 0
+		}
+		sum += ys[i]
+		n++
+	}
+	return append(grid, sum/float64(n))
+}
+
 const (
 	// wordJoiner is added between text marks in extracted text.
 	wordJoiner = ""
-	// lineJoiner is added between lines in extracted text.
+	// lineJoiner is the default separator that is added between lines in extracted text.
 	lineJoiner = "\n"
 )
 
 var (
 	wordJoinerLen = len(wordJoiner)
-	lineJoinerLen = len(lineJoiner)
 	// spaceMark is a special TextMark used for spaces.
 	spaceMark = TextMark{
 		Text:     " ",
 		Original: " ",
 		Meta:     true,
 	}
+	// tabMark is a special TextMark used for the gaps left by tab jumps in TJ arrays.
+	tabMark = TextMark{
+		Text:     "\t",
+		Original: "\t",
+		Meta:     true,
+	}
 )
 
 // sortPosition sorts a text list by its elements' positions on a page.
@@ -1225,10 +1901,17 @@ func (pt *PageText) sortPosition(tol float64) {
 		return
 	}
 
+	// The marks are sorted by index because textMarks are large and sorting them directly is
+	// slow on pages with many marks.
+	order := make([]int, len(pt.marks))
+	for i := range order {
+		order[i] = i
+	}
+
 	// For grouping data vertically into lines, it is necessary to have the data presorted by
 	// descending y position.
-	sort.SliceStable(pt.marks, func(i, j int) bool {
-		ti, tj := pt.marks[i], pt.marks[j]
+	sort.SliceStable(order, func(i, j int) bool {
+		ti, tj := &pt.marks[order[i]], &pt.marks[order[j]]
 		if ti.orient != tj.orient {
 			return ti.orient < tj.orient
 		}
@@ -1236,32 +1919,40 @@ func (pt *PageText) sortPosition(tol float64) {
 	})
 
 	// Cluster the marks into y-clusters by relative y proximity. Each cluster is our guess of what
-	// makes up a line of text.
+	// makes up a line of text. clusters[k] is the cluster of pt.marks[k].
 	clusters := make([]int, len(pt.marks))
 	cluster := 0
-	clusters[0] = cluster
-	for i := 1; i < len(pt.marks); i++ {
-		if pt.marks[i-1].orient != pt.marks[i].orient {
+	clusters[order[0]] = cluster
+	for i := 1; i < len(order); i++ {
+		prev, tm := &pt.marks[order[i-1]], &pt.marks[order[i]]
+		if prev.orient != tm.orient {
 			cluster++
 		} else {
-			if pt.marks[i-1].orientedStart.Y-pt.marks[i].orientedStart.Y > tol {
+			if prev.orientedStart.Y-tm.orientedStart.Y > tol {
 				cluster++
 			}
 		}
-		clusters[i] = cluster
+		clusters[order[i]] = cluster
 	}
 
 	// Sort by y-cluster and x.
-	sort.SliceStable(pt.marks, func(i, j int) bool {
-		ti, tj := pt.marks[i], pt.marks[j]
+	sort.SliceStable(order, func(i, j int) bool {
+		ki, kj := order[i], order[j]
+		ti, tj := &pt.marks[ki], &pt.marks[kj]
 		if ti.orient != tj.orient {
 			return ti.orient < tj.orient
 		}
-		if clusters[i] != clusters[j] {
-			return clusters[i] < clusters[j]
+		if clusters[ki] != clusters[kj] {
+			return clusters[ki] < clusters[kj]
 		}
 		return ti.orientedStart.X < tj.orientedStart.X
 	})
+
+	marks := make([]textMark, len(pt.marks))
+	for i, k := range order {
+		marks[i] = pt.marks[k]
+	}
+	pt.marks = marks
 }
 
 // textLine represents a line of text on a page.
@@ -1294,7 +1985,7 @@ func (pt PageText) toLines(tol float64) []textLine {
 	}
 	var lines []textLine
 	for _, o := range orientKeys(tlOrient) {
-		lns := PageText{marks: tlOrient[o]}.toLinesOrient(tol)
+		lns := PageText{marks: tlOrient[o], options: pt.options}.toLinesOrient(tol)
 		lines = append(lines, lns...)
 	}
 	return lines
@@ -1319,6 +2010,10 @@ func (pt PageText) toLinesOrient(tol float64) []textLine {
 	averageCharWidth := exponAve{}
 	wordSpacing := exponAve{}
 	lastEndX := 0.0 // lastEndX is pt.marks[i-1].orientedEnd.X
+	last := -1      // last is the index in `marks` of the TextMark for pt.marks[i-1].
+	space := spaceMark
+	space.Text = pt.options.inferredSpace()
+	overprintTol := pt.options.overprintTolerance()
 
 	for _, tm := range pt.marks {
 		if tm.orientedStart.Y+tol < y {
@@ -1327,7 +2022,7 @@ func (pt PageText) toLinesOrient(tol float64) []textLine {
 				if averageCharWidth.running {
 					// FIXME(peterwilliams97): Fix and reinstate combineDiacritics.
 					// tl = combineDiacritics(tl, averageCharWidth.ave)
-					tl = removeDuplicates(tl, averageCharWidth.ave)
+					tl = removeDuplicates(tl, averageCharWidth.ave*overprintTol)
 				}
 				lines = append(lines, tl)
 			}
@@ -1335,6 +2030,7 @@ func (pt PageText) toLinesOrient(tol float64) []textLine {
 			xx = []float64{}
 			y = tm.orientedStart.Y
 			scanning = false
+			last = -1
 		}
 
 		// Detect text movements that represent spaces on the printed page.
@@ -1365,12 +2061,20 @@ func (pt PageText) toLinesOrient(tol float64) []textLine {
 			nextWordX-tm.orientedStart.X, isSpace)
 
 		if isSpace {
-			marks = append(marks, spaceMark)
+			if tm.tabBefore {
+				marks = append(marks, tabMark)
+			} else {
+				marks = append(marks, space)
+			}
 			xx = append(xx, (lastEndX+tm.orientedStart.X)*0.5)
 		}
 
 		// Add the text to the line.
+		if last >= 0 {
+			marks[last].Gap = tm.orientedStart.X - lastEndX
+		}
 		lastEndX = tm.orientedEnd.X
+		last = len(marks)
 		marks = append(marks, tm.ToTextMark())
 		xx = append(xx, tm.orientedStart.X)
 		scanning = true
@@ -1379,7 +2083,7 @@ func (pt PageText) toLinesOrient(tol float64) []textLine {
 	if len(marks) > 0 {
 		tl := newLine(y, xx, marks)
 		if averageCharWidth.running {
-			tl = removeDuplicates(tl, averageCharWidth.ave)
+			tl = removeDuplicates(tl, averageCharWidth.ave*overprintTol)
 		}
 		lines = append(lines, tl)
 	}
@@ -1431,23 +2135,25 @@ func newLine(y float64, xx []float64, marks []TextMark) textLine {
 	}
 }
 
-// removeDuplicates returns `tl` with duplicate characters removed. `charWidth` is the average
-// character width for the line.
-func removeDuplicates(tl textLine, charWidth float64) textLine {
-	if len(tl.dxList) == 0 || len(tl.marks) == 0 {
+// removeDuplicates returns `tl` with duplicate characters removed. Characters with the same text
+// that are no more than `tol` apart are duplicates. Characters drawn with text knockout off are
+// not removed because they are intended to overlap.
+func removeDuplicates(tl textLine, tol float64) textLine {
+	if len(tl.dxList) == 0 || len(tl.marks) == 0 || tol < 0 {
 		return tl
 	}
-	// NOTE(peterwilliams97) 0.3 is a guess. It may be possible to tune this to a better value.
-	tol := charWidth * 0.3
 	marks := []TextMark{tl.marks[0]}
 	var dxList []float64
 
 	tm0 := tl.marks[0]
 	for i, dx := range tl.dxList {
 		tm := tl.marks[i+1]
-		if tm.Text != tm0.Text || dx > tol {
+		if tm.Text != tm0.Text || dx > tol || tm.overlapping || tm0.overlapping {
 			marks = append(marks, tm)
 			dxList = append(dxList, dx)
+		} else {
+			// The duplicate is where the kept mark is so its gap is the kept mark's gap.
+			marks[len(marks)-1].Gap = tm.Gap
 		}
 		tm0 = tm
 	}
@@ -1604,52 +2310,81 @@ var diacritics = map[rune]string{
 	0x204E: "\u0359",
 }
 
-// getCurrentFont returns the font on top of the font stack, or DefaultFont if the font stack is
-// empty.
-func (to *textObject) getCurrentFont() *model.PdfFont {
-	if to.fontStack.empty() {
+// getCurrentFont returns the font set by the last Tf operator, or DefaultFont if no font has been
+// set. The font was looked up in the font cache, or loaded and added to it, by getFont when the
+// Tf operator was processed. The bool return is true if DefaultFont is returned.
+func (to *textObject) getCurrentFont() (*model.PdfFont, bool) {
+	if to.state.tfont == nil {
 		common.Log.Debug("ERROR: No font defined. Using default.")
-		return model.DefaultFont()
+		return model.DefaultFont(), true
 	}
-	return to.fontStack.peek()
+	return to.state.tfont, false
 }
 
-// getFont returns the font named `name` if it exists in the page's resources or an error if it
-// doesn't. It caches the returned fonts.
-func (to *textObject) getFont(name string) (*model.PdfFont, error) {
-	if to.e.fontCache != nil {
-		to.e.accessCount++
-		entry, ok := to.e.fontCache[name]
-		if ok {
-			entry.access = to.e.accessCount
-			return entry.font, nil
-		}
-	}
+// setColors sets the colors of `to` to the colors of graphics state `gs`.
+func (to *textObject) setColors(gs contentstream.GraphicsState) {
+	to.gs.ColorspaceStroking = gs.ColorspaceStroking
+	to.gs.ColorspaceNonStroking = gs.ColorspaceNonStroking
+	to.gs.ColorStroking = gs.ColorStroking
+	to.gs.ColorNonStroking = gs.ColorNonStroking
+}
 
-	// Font not in cache. Load it.
-	font, err := to.getFontDirect(name)
+// getFillColor returns the fill color of text rendered by `to`.
+func (to *textObject) getFillColor() color.Color {
+	return pdfColorToGo(to.gs.ColorspaceNonStroking, to.gs.ColorNonStroking)
+}
+
+// pdfColorToGo returns PDF color `col` in colorspace `cs` as a Go color. Colors that can't be
+// converted to RGB are returned as black, the initial PDF color.
+func pdfColorToGo(cs model.PdfColorspace, col model.PdfColor) color.Color {
+	if cs == nil || col == nil {
+		return color.Black
+	}
+	rgb, err := cs.ColorToRGB(col)
 	if err != nil {
-		return nil, err
+		common.Log.Debug("pdfColorToGo: ColorToRGB failed. cs=%s col=%v err=%v", cs, col, err)
+		return color.Black
 	}
+	rgbColor, ok := rgb.(*model.PdfColorDeviceRGB)
+	if !ok {
+		common.Log.Debug("pdfColorToGo: not RGB. cs=%s col=%v rgb=%T", cs, col, rgb)
+		return color.Black
+	}
+	rgb8 := rgbColor.ToInteger(8)
+	return color.RGBA{R: uint8(rgb8[0]), G: uint8(rgb8[1]), B: uint8(rgb8[2]), A: 255}
+}
 
-	if to.e.fontCache != nil {
-		entry := fontEntry{font, to.e.accessCount}
+// getFont returns the font named `name` and the object it was loaded from if it exists in the
+// page's resources or an error if it doesn't. It caches the returned fonts.
+func (to *textObject) getFont(name string) (*model.PdfFont, core.PdfObject, error) {
+	fontObj, err := to.getFontDict(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	if font, ok := to.e.fontCache.get(fontObj); ok {
+		return font, fontObj, nil
+	}
 
-		// Eject a victim if the cache is full.
-		if len(to.e.fontCache) >= maxFontCache {
-			var names []string
-			for name := range to.e.fontCache {
-				names = append(names, name)
-			}
-			sort.Slice(names, func(i, j int) bool {
-				return to.e.fontCache[names[i]].access < to.e.fontCache[names[j]].access
-			})
-			delete(to.e.fontCache, names[0])
-		}
-		to.e.fontCache[name] = entry
+	// Font not in cache. Load it.
+	font, err := model.NewPdfFontFromPdfObject(fontObj)
+	if err != nil {
+		common.Log.Debug("getFont: NewPdfFontFromPdfObject failed. name=%#q err=%v", name, err)
+		return nil, nil, err
 	}
+	to.e.fontCache.put(fontObj, font)
+	return font, fontObj, nil
+}
 
-	return font, nil
+// fontCache is a simple LRU cache that is used to prevent redundant constructions of PdfFont's
+// from PDF objects. It is keyed by the font object rather than the font's resource name because
+// the same name can refer to different fonts in different resources. It is safe for concurrent
+// use so that it can be shared by the Extractors for the pages of a document.
+// A nil *fontCache caches nothing.
+// NOTE: This is not a conventional glyph cache. It only caches PdfFont's.
+type fontCache struct {
+	sync.Mutex
+	fonts       map[core.PdfObject]fontEntry
+	accessCount int64 // Used to set fontEntry.access to an incrementing number.
 }
 
 // fontEntry is a entry in the font cache.
@@ -1661,32 +2396,70 @@ type fontEntry struct {
 // maxFontCache is the maximum number of PdfFont's in fontCache.
 const maxFontCache = 10
 
-// getFontDirect returns the font named `name` if it exists in the page's resources or an error if
-// it doesn't. Accesses page resources directly (not cached).
-func (to *textObject) getFontDirect(name string) (*model.PdfFont, error) {
-	fontObj, err := to.getFontDict(name)
-	if err != nil {
-		return nil, err
+// newFontCache returns an empty fontCache.
+func newFontCache() *fontCache {
+	return &fontCache{fonts: map[core.PdfObject]fontEntry{}}
+}
+
+// get returns the cached font for font object `fontObj` if there is one.
+func (fc *fontCache) get(fontObj core.PdfObject) (*model.PdfFont, bool) {
+	if fc == nil || fontObj == nil {
+		return nil, false
 	}
-	font, err := model.NewPdfFontFromPdfObject(fontObj)
-	if err != nil {
-		common.Log.Debug("getFontDirect: NewPdfFontFromPdfObject failed. name=%#q err=%v", name, err)
+	fc.Lock()
+	defer fc.Unlock()
+	fc.accessCount++
+	entry, ok := fc.fonts[fontObj]
+	if !ok {
+		return nil, false
+	}
+	entry.access = fc.accessCount
+	fc.fonts[fontObj] = entry
+	return entry.font, true
+}
+
+// put adds `font`, loaded from font object `fontObj`, to the cache.
+func (fc *fontCache) put(fontObj core.PdfObject, font *model.PdfFont) {
+	if fc == nil || fontObj == nil {
+		return
+	}
+	fc.Lock()
+	defer fc.Unlock()
+
+	// Eject a victim if the cache is full.
+	if len(fc.fonts) >= maxFontCache {
+		var keys []core.PdfObject
+		for key := range fc.fonts {
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			return fc.fonts[keys[i]].access < fc.fonts[keys[j]].access
+		})
+		delete(fc.fonts, keys[0])
 	}
-	return font, err
+	fc.fonts[fontObj] = fontEntry{font, fc.accessCount}
 }
 
 // getFontDict returns the font dict with key `name` if it exists in the page's or form's Font
-// resources or an error if it doesn't.
+// resources, or in the resources of the page's ancestors in the page tree, or an error if it
+// doesn't.
 func (to *textObject) getFontDict(name string) (fontObj core.PdfObject, err error) {
 	resources := to.resources
-	if resources == nil {
+	if resources == nil && len(to.e.parentResources) == 0 {
 		common.Log.Debug("getFontDict. No resources. name=%#q", name)
 		return nil, nil
 	}
-	fontObj, found := resources.GetFontByName(core.PdfObjectName(name))
-	if !found {
-		common.Log.Debug("ERROR: getFontDict: Font not found: name=%#q", name)
-		return nil, errors.New("font not in resources")
+	if resources != nil {
+		if fontObj, found := resources.GetFontByName(core.PdfObjectName(name)); found {
+			return fontObj, nil
+		}
+	}
+	// Fonts may be inherited from the page's ancestors in the page tree.
+	for _, resources := range to.e.parentResources {
+		if fontObj, found := resources.GetFontByName(core.PdfObjectName(name)); found {
+			return fontObj, nil
+		}
 	}
-	return fontObj, nil
+	common.Log.Debug("ERROR: getFontDict: Font not found: name=%#q", name)
+	return nil, errors.New("font not in resources")
 }
@@ -0,0 +1,161 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package extractor
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+// ALTO returns the text of `pt` as an ALTO (Analyzed Layout and Text Object) XML document. ALTO
+// is widely used in digitization and archival workflows. The text is organized into TextBlocks
+// of TextLines of Strings (words) with their positions and sizes. Text blocks are the blocks of
+// successive, close, left aligned lines described in TextLine.SoftWrap.
+// Positions are measured in points from the top left corner of the page, with MeasurementUnit
+// "pixel" as for a 72 DPI image of the page.
+func (pt PageText) ALTO() (string, error) {
+	box := pt.mediaBox
+	if box.Urx <= box.Llx || box.Ury <= box.Lly {
+		return "", errors.New("page size unknown")
+	}
+	altoBox := func(b model.PdfRectangle) altoPosition {
+		return altoPosition{
+			HPos:   altoFloat(b.Llx - box.Llx),
+			VPos:   altoFloat(box.Ury - b.Ury),
+			Width:  altoFloat(b.Urx - b.Llx),
+			Height: altoFloat(b.Ury - b.Lly),
+		}
+	}
+
+	page := altoPage{
+		ID:       "P1",
+		Width:    altoFloat(box.Urx - box.Llx),
+		Height:   altoFloat(box.Ury - box.Lly),
+		ImageNum: 1,
+	}
+	page.PrintSpace.altoPosition = altoBox(box)
+	for i, block := range textBlocks(pt.Lines()) {
+		ab := altoTextBlock{ID: fmt.Sprintf("P1_TB%d", i+1)}
+		bbox := block[0].BBox
+		for j, tl := range block {
+			bbox = rectUnion(bbox, tl.BBox)
+			al := altoTextLine{
+				ID:           fmt.Sprintf("%s_TL%d", ab.ID, j+1),
+				altoPosition: altoBox(tl.BBox),
+			}
+			for k, word := range lineWords(tl.Marks.Elements()) {
+				wbox, _ := marksBBox(word)
+				if k > 0 {
+					al.Items = append(al.Items, altoSpace{})
+				}
+				al.Items = append(al.Items, altoString{
+					ID:           fmt.Sprintf("%s_S%d", al.ID, k+1),
+					Content:      marksText(word),
+					altoPosition: altoBox(wbox),
+				})
+			}
+			ab.Lines = append(ab.Lines, al)
+		}
+		ab.altoPosition = altoBox(bbox)
+		page.PrintSpace.Blocks = append(page.PrintSpace.Blocks, ab)
+	}
+
+	doc := altoDoc{
+		Xmlns:           "http://www.loc.gov/standards/alto/ns-v4#",
+		MeasurementUnit: "pixel",
+		Page:            page,
+	}
+	data, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return xml.Header + string(data) + "\n", nil
+}
+
+// lineWords returns the marks in `marks`, the marks of a line, split into words at spaces.
+func lineWords(marks []TextMark) [][]TextMark {
+	var words [][]TextMark
+	var word []TextMark
+	for _, tm := range marks {
+		if tm.Meta || isTextSpace(tm.Text) {
+			if len(word) > 0 {
+				words = append(words, word)
+				word = nil
+			}
+			continue
+		}
+		word = append(word, tm)
+	}
+	if len(word) > 0 {
+		words = append(words, word)
+	}
+	return words
+}
+
+// altoFloat is a coordinate in an ALTO document. It is written with 2 decimal places.
+type altoFloat float64
+
+// MarshalXMLAttr implements xml.MarshalerAttr.
+func (f altoFloat) MarshalXMLAttr(name xml.Name) (xml.Attr, error) {
+	v := math.Round(float64(f)*100) / 100
+	return xml.Attr{Name: name, Value: fmt.Sprintf("%g", v)}, nil
+}
+
+// The following types are the elements of an ALTO document.
+
+type altoDoc struct {
+	XMLName         xml.Name `xml:"alto"`
+	Xmlns           string   `xml:"xmlns,attr"`
+	MeasurementUnit string   `xml:"Description>MeasurementUnit"`
+	Page            altoPage `xml:"Layout>Page"`
+}
+
+type altoPosition struct {
+	HPos   altoFloat `xml:"HPOS,attr"`
+	VPos   altoFloat `xml:"VPOS,attr"`
+	Width  altoFloat `xml:"WIDTH,attr"`
+	Height altoFloat `xml:"HEIGHT,attr"`
+}
+
+type altoPage struct {
+	ID         string    `xml:"ID,attr"`
+	Width      altoFloat `xml:"WIDTH,attr"`
+	Height     altoFloat `xml:"HEIGHT,attr"`
+	ImageNum   int       `xml:"PHYSICAL_IMG_NR,attr"`
+	PrintSpace struct {
+		altoPosition
+		Blocks []altoTextBlock `xml:"TextBlock"`
+	} `xml:"PrintSpace"`
+}
+
+type altoTextBlock struct {
+	ID string `xml:"ID,attr"`
+	altoPosition
+	Lines []altoTextLine `xml:"TextLine"`
+}
+
+type altoTextLine struct {
+	ID string `xml:"ID,attr"`
+	altoPosition
+	Items []interface{} // The altoStrings and altoSpaces in the line.
+}
+
+// altoString is a word.
+type altoString struct {
+	XMLName xml.Name `xml:"String"`
+	ID      string   `xml:"ID,attr"`
+	Content string   `xml:"CONTENT,attr"`
+	altoPosition
+}
+
+// altoSpace is a space between words.
+type altoSpace struct {
+	XMLName xml.Name `xml:"SP"`
+}
@@ -0,0 +1,92 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package extractor
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+// TestALTO checks the ALTO XML produced by PageText.ALTO().
+func TestALTO(t *testing.T) {
+	contents := `
+        BT
+        /UniDocCourier 10 Tf
+        12 TL
+        10 700 Td (Hello world) Tj
+        (Second line) '
+        0 -100 Td (Another block) Tj
+        ET`
+	pt := fragmentPageText(t, contents)
+	alto, err := pt.ALTO()
+	if err != nil {
+		t.Fatalf("ALTO failed. err=%v", err)
+	}
+	var doc struct {
+		Page struct {
+			Width  float64 `xml:"WIDTH,attr"`
+			Height float64 `xml:"HEIGHT,attr"`
+			Blocks []struct {
+				Lines []struct {
+					Strings []struct {
+						Content string  `xml:"CONTENT,attr"`
+						HPos    float64 `xml:"HPOS,attr"`
+						VPos    float64 `xml:"VPOS,attr"`
+						Width   float64 `xml:"WIDTH,attr"`
+					} `xml:"String"`
+					Spaces []struct{} `xml:"SP"`
+				} `xml:"TextLine"`
+			} `xml:"PrintSpace>TextBlock"`
+		} `xml:"Layout>Page"`
+	}
+	if err := xml.Unmarshal([]byte(alto), &doc); err != nil {
+		t.Fatalf("Unmarshal failed. err=%v\n%s", err, alto)
+	}
+	page := doc.Page
+	if page.Width != 612 || page.Height != 792 || len(page.Blocks) != 2 {
+		t.Fatalf("incorrect page %+v\n%s", page, alto)
+	}
+	var blockTexts []string
+	for _, block := range page.Blocks {
+		var lines []string
+		for _, line := range block.Lines {
+			var words []string
+			for _, s := range line.Strings {
+				words = append(words, s.Content)
+			}
+			if len(line.Spaces) != len(words)-1 {
+				t.Fatalf("%d spaces in line %q", len(line.Spaces), words)
+			}
+			lines = append(lines, strings.Join(words, " "))
+		}
+		blockTexts = append(blockTexts, strings.Join(lines, "|"))
+	}
+	if got := strings.Join(blockTexts, "||"); got != "Hello world|Second line||Another block" {
+		t.Fatalf("incorrect text %q\n%s", got, alto)
+	}
+	// "world" starts 6 Courier characters to the right of x=10 and its top is at y=710, which is
+	// 82 points from the top of the page.
+	world := page.Blocks[0].Lines[0].Strings[1]
+	if world.HPos != 46 || world.VPos != 82 || world.Width != 30 {
+		t.Fatalf("incorrect position %+v\n%s", world, alto)
+	}
+}
+
+// TestALTOTruncated checks that ALTO() works on pages whose text is truncated because the
+// extractor is unlicensed.
+func TestALTOTruncated(t *testing.T) {
+	contents := "BT /UniDocCourier 10 Tf 12 TL 10 700 Td " +
+		strings.Repeat("(Some long body text that fills the page.) ' ", 10) + "ET"
+	pt := unlicensedPageText(t, contents)
+	alto, err := pt.ALTO()
+	if err != nil {
+		t.Fatalf("ALTO failed. err=%v", err)
+	}
+	if !strings.Contains(alto, `CONTENT="fills"`) {
+		t.Fatalf("ALTO doesn't contain the page's words. alto=%s", alto)
+	}
+}
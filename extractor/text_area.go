@@ -0,0 +1,92 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package extractor
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+// ApplyArea restricts `pt` to the text marks whose centers are inside `area`, and lays out the
+// remaining text again.
+// The marks are looked up in an index that is built once for each extracted page, so selecting
+// areas repeatedly, e.g. for rubber band selection in an interactive tool, costs an index query
+// rather than a new extraction. To select several areas from the same page in turn, apply each
+// area to a copy of the page's PageText:
+//
+//	selection := *pt
+//	selection.ApplyArea(area)
+//	text := selection.Text()
+func (pt *PageText) ApplyArea(area model.PdfRectangle) {
+	pt.ApplyAreas([]model.PdfRectangle{area})
+}
+
+// ApplyAreas restricts `pt` to the text marks whose centers are inside any of `areas`, and lays
+// out the remaining text again. See ApplyArea.
+func (pt *PageText) ApplyAreas(areas []model.PdfRectangle) {
+	index := pt.index
+	if index == nil {
+		index = &markIndex{}
+	}
+	selected := index.query(pt.marks, areas)
+	marks := make([]textMark, len(selected))
+	for i, j := range selected {
+		marks[i] = pt.marks[j]
+	}
+	pt.marks = marks
+	pt.index = &markIndex{}
+	pt.computeViews()
+	procBuf(pt)
+}
+
+// markIndex is a spatial index of the text marks of a PageText. It is built on the first query
+// and shared by copies of the PageText, so it must be replaced when the marks change.
+type markIndex struct {
+	once    sync.Once
+	order   []int     // Indexes of the marks sorted by the x coordinates of their centers.
+	centers []float64 // Sorted x coordinates of the centers of the marks.
+}
+
+// query returns the indexes, in increasing order, of the marks in `marks` whose centers are inside
+// any of `areas`.
+func (index *markIndex) query(marks []textMark, areas []model.PdfRectangle) []int {
+	index.once.Do(func() { index.build(marks) })
+	inside := make(map[int]bool)
+	for _, area := range areas {
+		i := sort.SearchFloat64s(index.centers, area.Llx)
+		for ; i < len(index.centers) && index.centers[i] <= area.Urx; i++ {
+			j := index.order[i]
+			y := (marks[j].bbox.Lly + marks[j].bbox.Ury) / 2
+			if area.Lly <= y && y <= area.Ury {
+				inside[j] = true
+			}
+		}
+	}
+	selected := make([]int, 0, len(inside))
+	for j := range inside {
+		selected = append(selected, j)
+	}
+	sort.Ints(selected)
+	return selected
+}
+
+// build builds `index` for `marks`.
+func (index *markIndex) build(marks []textMark) {
+	index.order = make([]int, len(marks))
+	for i := range marks {
+		index.order[i] = i
+	}
+	centerX := func(tm textMark) float64 { return (tm.bbox.Llx + tm.bbox.Urx) / 2 }
+	sort.SliceStable(index.order, func(i, j int) bool {
+		return centerX(marks[index.order[i]]) < centerX(marks[index.order[j]])
+	})
+	index.centers = make([]float64, len(marks))
+	for i, j := range index.order {
+		index.centers[i] = centerX(marks[j])
+	}
+}
@@ -0,0 +1,59 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package extractor
+
+import (
+	"testing"
+
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+// TestApplyArea checks that PageText.ApplyArea and ApplyAreas keep the text inside the areas and
+// that areas can be applied to copies of a PageText without changing it.
+func TestApplyArea(t *testing.T) {
+	contents := `
+        BT
+        /UniDocCourier 10 Tf
+        1 0 0 1 10 700 Tm (Top left) Tj
+        1 0 0 1 300 700 Tm (Top right) Tj
+        1 0 0 1 10 600 Tm (Bottom left) Tj
+        1 0 0 1 300 600 Tm (Bottom right) Tj
+        ET`
+	pt := fragmentPageText(t, contents)
+	all := pt.Text()
+	left := model.PdfRectangle{Llx: 0, Lly: 550, Urx: 200, Ury: 750}
+	top := model.PdfRectangle{Llx: 0, Lly: 650, Urx: 500, Ury: 750}
+	bottomRight := model.PdfRectangle{Llx: 250, Lly: 550, Urx: 500, Ury: 650}
+	for _, test := range []struct {
+		areas    []model.PdfRectangle
+		expected string
+	}{
+		{[]model.PdfRectangle{left}, "Top left\nBottom left"},
+		{[]model.PdfRectangle{top}, "Top left Top right"},
+		{[]model.PdfRectangle{top, bottomRight}, "Top left Top right\nBottom right"},
+		{nil, ""},
+	} {
+		selection := *pt
+		if len(test.areas) == 1 {
+			selection.ApplyArea(test.areas[0])
+		} else {
+			selection.ApplyAreas(test.areas)
+		}
+		if text := selection.Text(); text != test.expected {
+			t.Fatalf("areas=%v: text=%q expected %q", test.areas, text, test.expected)
+		}
+		if text := pt.Text(); text != all {
+			t.Fatalf("areas=%v: original text changed to %q", test.areas, text)
+		}
+	}
+
+	// Areas applied to a selection select from the selection.
+	pt.ApplyArea(left)
+	pt.ApplyArea(top)
+	if text := pt.Text(); text != "Top left" {
+		t.Fatalf("text=%q expected %q", text, "Top left")
+	}
+}
@@ -0,0 +1,134 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package extractor
+
+import (
+	"fmt"
+	"unicode"
+
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+// TextDirection is the direction that a run of text is read in.
+type TextDirection int
+
+// Text directions.
+const (
+	DirectionLTR TextDirection = iota // Left to right, e.g. Latin, Greek and Cyrillic scripts.
+	DirectionRTL                      // Right to left, e.g. Hebrew and Arabic scripts.
+)
+
+// String returns a string describing `d`.
+func (d TextDirection) String() string {
+	if d == DirectionRTL {
+		return "RTL"
+	}
+	return "LTR"
+}
+
+// DirectionRun is a run of text in a line that is all read in the same direction.
+type DirectionRun struct {
+	// Text is the text of the run's marks. It is the substring Text()[Offset:Offset+len(Text)] of
+	// the page's extracted text unless that text has been truncated.
+	Text string
+	// Offset is the offset of the start of the run in the page's extracted text.
+	Offset int
+	// Marks are the TextMarks of the run.
+	Marks *TextMarkArray
+	// BBox is the bounding box of the run's text.
+	BBox model.PdfRectangle
+	// Direction is the direction the run is read in.
+	Direction TextDirection
+}
+
+// String returns a string describing `r`.
+func (r DirectionRun) String() string {
+	return fmt.Sprintf("{DirectionRun: %d %s %q}", r.Offset, r.Direction, r.Text)
+}
+
+// DirectionRuns returns the runs of left to right and right to left text in `tl`, in the order
+// they appear in the line. The line's text is in page order, left to right, so the text of right
+// to left runs is not in reading order. The runs are the boundaries that a bidirectional
+// algorithm would reorder text at, so callers can apply their own bidi algorithm to them.
+// The direction of text is set by its script. Text with no direction, such as digits,
+// punctuation and spaces, takes the direction of the text around it or, between text with
+// different directions, the direction of the text before it.
+func (tl TextLine) DirectionRuns() []DirectionRun {
+	marks := tl.Marks.Elements()
+	if len(marks) == 0 {
+		return nil
+	}
+	dirs := resolveDirections(marks)
+	var runs []DirectionRun
+	start := 0
+	for i := 1; i <= len(marks); i++ {
+		if i < len(marks) && dirs[i] == dirs[start] {
+			continue
+		}
+		run := marks[start:i]
+		bbox, _ := marksBBox(run)
+		runs = append(runs, DirectionRun{
+			Text:      marksText(run),
+			Offset:    run[0].Offset,
+			Marks:     &TextMarkArray{marks: run},
+			BBox:      bbox,
+			Direction: dirs[start],
+		})
+		start = i
+	}
+	return runs
+}
+
+// resolveDirections returns the directions of `marks`. Marks with no strong direction take the
+// direction of the marks with strong directions around them as described in DirectionRuns.
+func resolveDirections(marks []TextMark) []TextDirection {
+	strong := make([]int, len(marks)) // 1 for LTR, -1 for RTL, 0 for no strong direction.
+	for i, tm := range marks {
+		strong[i] = strongDirection(tm.Text)
+	}
+	// Marks before the first strong mark take its direction. After it, marks with no strong
+	// direction take the direction of the last strong mark before them.
+	prev := 0
+	for _, d := range strong {
+		if d != 0 {
+			prev = d
+			break
+		}
+	}
+	dirs := make([]TextDirection, len(marks))
+	for i := range marks {
+		d := strong[i]
+		if d == 0 {
+			d = prev
+		} else {
+			prev = d
+		}
+		if d < 0 {
+			dirs[i] = DirectionRTL
+		}
+	}
+	return dirs
+}
+
+// rtlScripts are the scripts that are written right to left.
+var rtlScripts = []*unicode.RangeTable{
+	unicode.Arabic, unicode.Hebrew, unicode.Nko, unicode.Syriac, unicode.Thaana,
+}
+
+// strongDirection returns -1 if the first letter in `text` is in a right to left script, 1 if it
+// is in another script, or 0 if `text` has no letters.
+func strongDirection(text string) int {
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		if unicode.In(r, rtlScripts...) {
+			return -1
+		}
+		return 1
+	}
+	return 0
+}
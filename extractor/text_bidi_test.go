@@ -0,0 +1,108 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package extractor
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/unidoc/unipdf/v3/core"
+)
+
+// TestDirectionRuns checks that TextLine.DirectionRuns() splits a line of mixed Latin and Hebrew
+// text into left to right and right to left runs and that digits and spaces join the run before
+// them.
+func TestDirectionRuns(t *testing.T) {
+	cmap := `/CIDInit /ProcSet findresource begin
+12 dict begin
+begincmap
+/CMapName /Hebrew def
+/CMapType 2 def
+1 begincodespacerange
+<00> <FF>
+endcodespacerange
+2 beginbfchar
+<41> <05D0>
+<42> <05D1>
+endbfchar
+endcmap
+CMapName currentdict /CMap defineresource pop
+end
+end`
+	toUnicode, err := core.MakeStream([]byte(cmap), core.NewRawEncoder())
+	if err != nil {
+		t.Fatalf("MakeStream failed. err=%v", err)
+	}
+	fontDict := core.MakeDict()
+	fontDict.Set("Type", core.MakeName("Font"))
+	fontDict.Set("Subtype", core.MakeName("TrueType"))
+	fontDict.Set("BaseFont", core.MakeName("Hebrew"))
+	fontDict.Set("FirstChar", core.MakeInteger(65))
+	fontDict.Set("LastChar", core.MakeInteger(66))
+	fontDict.Set("Widths", core.MakeArrayFromIntegers([]int{600, 600}))
+	fontDict.Set("ToUnicode", toUnicode)
+	resources := fragmentResources()
+	resources.SetFontByName("Hebrew", fontDict)
+	contents := `
+        BT
+        /UniDocCourier 10 Tf 10 700 Td (abc ) Tj
+        /Hebrew 10 Tf (BA) Tj
+        /UniDocCourier 10 Tf ( 12 def) Tj
+        ET`
+
+	e := NewFromContents(contents, resources, fragmentMediaBox)
+	pt, _, _, err := e.ExtractPageText()
+	if err != nil {
+		t.Fatalf("ExtractPageText failed. err=%v", err)
+	}
+	lines := pt.Lines()
+	if len(lines) != 1 {
+		t.Fatalf("%d lines expected 1. lines=%v", len(lines), lines)
+	}
+	expected := []struct {
+		text      string
+		direction TextDirection
+	}{
+		{"abc ", DirectionLTR},
+		{"בא 12 ", DirectionRTL},
+		{"def", DirectionLTR},
+	}
+	runs := lines[0].DirectionRuns()
+	if len(runs) != len(expected) {
+		t.Fatalf("%d runs expected %d. runs=%v", len(runs), len(expected), runs)
+	}
+	text := pt.Text()
+	for i, r := range runs {
+		exp := expected[i]
+		if r.Text != exp.text || r.Direction != exp.direction {
+			t.Fatalf("run %d: %s expected %q %s", i, r, exp.text, exp.direction)
+		}
+		if text[r.Offset:r.Offset+len(r.Text)] != r.Text {
+			t.Fatalf("run %d: %s inconsistent with text %q", i, r, text)
+		}
+	}
+}
+
+// TestDirectionRunsTruncated checks that DirectionRuns() works on pages whose text is truncated
+// because the extractor is unlicensed.
+func TestDirectionRunsTruncated(t *testing.T) {
+	contents := "BT /UniDocCourier 10 Tf 12 TL 10 700 Td " +
+		strings.Repeat("(Some long body text that fills the page.) ' ", 10) + "ET"
+	pt := unlicensedPageText(t, contents)
+	lines := pt.Lines()
+	if len(lines) == 0 {
+		t.Fatalf("no lines")
+	}
+	for i, tl := range lines {
+		var text string
+		for _, r := range tl.DirectionRuns() {
+			text += r.Text
+		}
+		if text != tl.Text {
+			t.Fatalf("line %d: runs text=%q expected %q", i, text, tl.Text)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package extractor
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+// FormBlank is a fill-in-the-blank field of a paper-style form, such as "Name: ________", where
+// the blank is a horizontal ruling that follows a text label on the same line.
+type FormBlank struct {
+	// Label is the text before the blank, e.g. "Name:".
+	Label string
+	// BlankBBox is the area above the ruling where the blank is filled in. It extends from the
+	// ruling to the top of the label's line.
+	BlankBBox model.PdfRectangle
+}
+
+// String returns a string describing `b`.
+func (b FormBlank) String() string {
+	r := b.BlankBBox
+	return fmt.Sprintf("{FormBlank: %q (%5.1f, %5.1f) (%5.1f, %5.1f)}", b.Label, r.Llx, r.Lly,
+		r.Urx, r.Ury)
+}
+
+// FormBlanks returns the fill-in-the-blank fields of `pt`. These are horizontal rulings near the
+// baseline of a line of text that are mostly not covered by text, paired with the text to their
+// left on the line. The text of a label starts after the previous blank on the line, so lines
+// like "Name: ______ Date: ______" give 2 blanks. Blanks are returned in the order of the lines
+// and from left to right. Rulings that underline text and blanks with no label are skipped.
+// This gives the structure of forms even when their fields are empty.
+func (pt PageText) FormBlanks() []FormBlank {
+	var rulings []Ruling
+	for _, r := range pt.Rulings() {
+		if r.Kind == RulingHorizontal {
+			rulings = append(rulings, r)
+		}
+	}
+	sort.Slice(rulings, func(i, j int) bool { return rulings[i].Lo < rulings[j].Lo })
+
+	var blanks []FormBlank
+	for _, tl := range pt.Lines() {
+		h := tl.BBox.Ury - tl.BBox.Lly
+		if h <= 0 {
+			continue
+		}
+		left := math.Inf(-1) // The right end of the previous blank on the line.
+		for _, r := range rulings {
+			if math.Abs(r.Primary-tl.BBox.Lly) > blankBaselineTol*h ||
+				r.Hi <= tl.BBox.Llx || r.Lo > tl.BBox.Urx+blankLabelGap*h {
+				continue
+			}
+			if textCoverage(tl, r.Lo, r.Hi) > blankMaxCoverage {
+				continue
+			}
+			label := blankLabel(tl, left, r.Lo, h)
+			left = r.Hi
+			if label == "" {
+				continue
+			}
+			blanks = append(blanks, FormBlank{
+				Label:     label,
+				BlankBBox: model.PdfRectangle{Llx: r.Lo, Lly: r.Primary, Urx: r.Hi, Ury: tl.BBox.Ury},
+			})
+		}
+	}
+	return blanks
+}
+
+const (
+	// blankBaselineTol is the maximum distance of a blank's ruling from the baseline of its line
+	// as a fraction of the line height.
+	blankBaselineTol = 0.5
+	// blankMaxCoverage is the maximum fraction of a blank's ruling that is covered by text. Rulings
+	// that are more covered are underlines.
+	blankMaxCoverage = 0.5
+	// blankLabelGap is the maximum gap between a label and its blank as a fraction of the line
+	// height.
+	blankLabelGap = 3.0
+)
+
+// textCoverage returns the fraction of the range `lo` to `hi` on the x axis that is covered by the
+// non-space text of `tl`.
+func textCoverage(tl TextLine, lo, hi float64) float64 {
+	covered := 0.0
+	for _, tm := range tl.Marks.Elements() {
+		if tm.Meta || isTextSpace(tm.Text) {
+			continue
+		}
+		covered += math.Max(0, math.Min(hi, tm.BBox.Urx)-math.Max(lo, tm.BBox.Llx))
+	}
+	return covered / (hi - lo)
+}
+
+// blankLabel returns the text of `tl` between `left` and `right` on the x axis, which ends no more
+// than blankLabelGap line heights `h` before `right`. It returns "" if there is no such text.
+func blankLabel(tl TextLine, left, right, h float64) string {
+	marks := tl.Marks.Elements()
+	i0, i1 := -1, -1 // The first and last label marks.
+	for i, tm := range marks {
+		if tm.Meta || tm.BBox.Llx < left || tm.BBox.Urx > right+rulingTol {
+			continue
+		}
+		if i0 < 0 {
+			i0 = i
+		}
+		i1 = i
+	}
+	if i0 < 0 {
+		return ""
+	}
+	if right-marks[i1].BBox.Urx > blankLabelGap*h {
+		return ""
+	}
+	return strings.TrimSpace(marksText(marks[i0 : i1+1]))
+}
@@ -0,0 +1,70 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package extractor
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+// TestFormBlanks checks that PageText.FormBlanks() pairs labels with the rulings that follow them
+// and skips underlines.
+func TestFormBlanks(t *testing.T) {
+	contents := `
+        0.5 w
+        45 699 m 200 699 l S
+        240 699 m 300 699 l S
+        10 648 m 88 648 l S
+        70 599 m 200 599 l S
+        BT
+        /UniDocHelvetica 10 Tf
+        1 0 0 1 10 700 Tm (Name:) Tj
+        1 0 0 1 210 700 Tm (Date:) Tj
+        1 0 0 1 10 650 Tm (Underlined title) Tj
+        1 0 0 1 10 600 Tm (Signature:) Tj
+        1 0 0 1 80 600 Tm (J. Smith) Tj
+        ET`
+	pt := fragmentPageText(t, contents)
+	expected := []FormBlank{
+		{"Name:", model.PdfRectangle{Llx: 45, Lly: 699, Urx: 200, Ury: 710}},
+		{"Date:", model.PdfRectangle{Llx: 240, Lly: 699, Urx: 300, Ury: 710}},
+		{"Signature:", model.PdfRectangle{Llx: 70, Lly: 599, Urx: 200, Ury: 610}},
+	}
+	blanks := pt.FormBlanks()
+	if len(blanks) != len(expected) {
+		t.Fatalf("%d blanks expected %d. blanks=%v", len(blanks), len(expected), blanks)
+	}
+	for i, b := range blanks {
+		exp := expected[i]
+		r, e := b.BlankBBox, exp.BlankBBox
+		if b.Label != exp.Label || math.Abs(r.Llx-e.Llx) > 0.01 || math.Abs(r.Lly-e.Lly) > 0.01 ||
+			math.Abs(r.Urx-e.Urx) > 0.01 || math.Abs(r.Ury-e.Ury) > 0.01 {
+			t.Fatalf("blank %d: %s expected %s", i, b, exp)
+		}
+	}
+}
+
+// TestFormBlanksTruncated checks that FormBlanks() works on pages whose text is truncated because
+// the extractor is unlicensed.
+func TestFormBlanksTruncated(t *testing.T) {
+	contents := `
+        0.5 w
+        45 699 m 200 699 l S
+        BT
+        /UniDocHelvetica 10 Tf
+        1 0 0 1 10 700 Tm (Name:) Tj
+        1 0 0 1 10 680 Tm 12 TL
+        ` + strings.Repeat("(Some long body text that fills the page.) '\n", 10) + `
+        ET`
+	pt := unlicensedPageText(t, contents)
+	blanks := pt.FormBlanks()
+	if len(blanks) != 1 || blanks[0].Label != "Name:" {
+		t.Fatalf("blanks=%v expected 1 blank labeled %q", blanks, "Name:")
+	}
+}
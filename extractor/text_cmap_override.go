@@ -0,0 +1,171 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package extractor
+
+import (
+	"strings"
+
+	"github.com/unidoc/unipdf/v3/internal/cmap"
+	"github.com/unidoc/unipdf/v3/internal/textencoding"
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+// SetCMapOverride makes `e` decode the text of the font named `fontName` with the CMap in `data`
+// instead of the font's own encoding and ToUnicode CMap. This fixes the text of documents whose
+// fonts have known bad CMaps, e.g. documents made from a template with a broken CJK font.
+// `fontName` is the font's BaseFont, with or without a subset tag such as "ABCDEF+".
+// The override's codespace ranges split the font's strings into character codes. Its bfchar and
+// bfrange mappings give the text of codes. Its cidrange mappings give the CIDs of codes, which
+// are used for the glyph widths and, for codes without a bf mapping, decoded with the predefined
+// Unicode CMap of the override's CIDSystemInfo, e.g. Adobe-Japan1-UCS2. Character codes that the
+// override doesn't map are decoded as before. Overrides are kept when `e` is Reset for another
+// page. A nil `data` removes the override for `fontName`.
+func (e *Extractor) SetCMapOverride(fontName string, data []byte) error {
+	overrides, err := withCMapOverride(e.cmapOverrides, fontName, data)
+	if err != nil {
+		return err
+	}
+	e.cmapOverrides = overrides
+	return nil
+}
+
+// SetCMapOverride makes `d` decode the text of the font named `fontName` on all the document's
+// pages with the CMap in `data`. See Extractor.SetCMapOverride. Cached pages and
+// repeated text that were extracted before the override was set are extracted again.
+func (d *DocumentExtractor) SetCMapOverride(fontName string, data []byte) error {
+	d.pageLock.Lock()
+	overrides, err := withCMapOverride(d.cmapOverrides, fontName, data)
+	if err == nil {
+		d.cmapOverrides = overrides
+	}
+	d.pageLock.Unlock()
+	if err != nil {
+		return err
+	}
+	d.repeatLock.Lock()
+	d.repeated = nil
+	d.repeatLock.Unlock()
+	return nil
+}
+
+// withCMapOverride returns a copy of `overrides` with the override for the font named `fontName`
+// set to the CMap in `data`, or removed if `data` is nil. `overrides` is not changed, so it can be
+// shared by Extractors that are running concurrently.
+func withCMapOverride(overrides map[string]*cmapOverride, fontName string,
+	data []byte) (map[string]*cmapOverride, error) {
+	var override *cmapOverride
+	if data != nil {
+		var err error
+		override, err = newCMapOverride(data)
+		if err != nil {
+			return nil, err
+		}
+	}
+	fontName = stripSubsetTag(fontName)
+	copied := make(map[string]*cmapOverride, len(overrides)+1)
+	for name, o := range overrides {
+		copied[name] = o
+	}
+	if override == nil {
+		delete(copied, fontName)
+	} else {
+		copied[fontName] = override
+	}
+	return copied, nil
+}
+
+// cmapOverride is a CMap that replaces the encoding and ToUnicode CMap of a font.
+type cmapOverride struct {
+	// data is the CMap's data. It identifies the override in the page cache keys.
+	data []byte
+	cmap *cmap.CMap
+	// cidToUnicode is the predefined CMap that maps the CIDs of cmap's character collection to
+	// Unicode. It is nil if the collection has no predefined Unicode CMap.
+	cidToUnicode *cmap.CMap
+}
+
+// newCMapOverride returns the CMap override with CMap data `data`.
+func newCMapOverride(data []byte) (*cmapOverride, error) {
+	cm, err := cmap.LoadCmapFromData(data, false)
+	if err != nil {
+		return nil, err
+	}
+	override := &cmapOverride{data: data, cmap: cm}
+	if info := cm.SystemInfo(); info.Registry != "" && info.Ordering != "" {
+		name := info.Registry + "-" + info.Ordering + "-UCS2"
+		if cmap.IsPredefinedCMap(name) {
+			override.cidToUnicode, err = cmap.LoadPredefinedCMap(name)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return override, nil
+}
+
+// cmapOverride returns the CMap override for `font` or nil if there isn't one.
+func (e *Extractor) cmapOverride(font *model.PdfFont) *cmapOverride {
+	if len(e.cmapOverrides) == 0 || font == nil {
+		return nil
+	}
+	return e.cmapOverrides[stripSubsetTag(font.BaseFont())]
+}
+
+// charcodes returns the character codes in string `data`, split with the codespace ranges of the
+// override. It returns `charcodes`, the codes the font split `data` into, if `data` doesn't match
+// the override's codespaces.
+func (o *cmapOverride) charcodes(data []byte,
+	charcodes []textencoding.CharCode) []textencoding.CharCode {
+	codes, ok := o.cmap.BytesToCharcodes(data)
+	if !ok {
+		return charcodes
+	}
+	charcodes = make([]textencoding.CharCode, len(codes))
+	for i, code := range codes {
+		charcodes[i] = textencoding.CharCode(code)
+	}
+	return charcodes
+}
+
+// cid returns the CID that the override maps character code `code` to.
+func (o *cmapOverride) cid(code textencoding.CharCode) (textencoding.CharCode, bool) {
+	cid, ok := o.cmap.CharcodeToCID(cmap.CharCode(code))
+	return textencoding.CharCode(cid), ok
+}
+
+// decode replaces the texts and decodings of the character codes `charcodes` that the override
+// maps with their mappings in the override. It returns the number of codes that were undecodable
+// before and are now decoded.
+func (o *cmapOverride) decode(charcodes []textencoding.CharCode, texts []string,
+	decodings []model.CharcodeDecoding) int {
+	fixed := 0
+	for i, code := range charcodes {
+		s, ok := o.cmap.CharcodeToUnicode(cmap.CharCode(code))
+		if !ok && o.cidToUnicode != nil {
+			if cid, isCID := o.cmap.CharcodeToCID(cmap.CharCode(code)); isCID {
+				s, ok = o.cidToUnicode.CharcodeToUnicode(cid)
+			}
+		}
+		if !ok {
+			continue
+		}
+		if decodings[i] == model.CharcodeDecodingMissing {
+			fixed++
+		}
+		texts[i] = s
+		decodings[i] = model.CharcodeDecodingToUnicode
+	}
+	return fixed
+}
+
+// stripSubsetTag returns font name `name` without the tag that starts the names of font subsets,
+// e.g. "MSMincho" for "ABCDEF+MSMincho".
+func stripSubsetTag(name string) string {
+	if len(name) > 7 && name[6] == '+' && strings.ToUpper(name[:6]) == name[:6] {
+		return name[7:]
+	}
+	return name
+}
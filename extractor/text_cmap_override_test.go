@@ -0,0 +1,212 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package extractor
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/unidoc/unipdf/v3/core"
+)
+
+// TestCMapOverride checks that Extractor.SetCMapOverride replaces the ToUnicode CMap of a font,
+// that codes the override doesn't map are decoded with the font's CMap and that removing the
+// override restores the font's text.
+func TestCMapOverride(t *testing.T) {
+	cmap := `/CIDInit /ProcSet findresource begin
+12 dict begin
+begincmap
+/CMapName /Broken def
+/CMapType 2 def
+1 begincodespacerange
+<0000> <FFFF>
+endcodespacerange
+2 beginbfchar
+<0041> <0058>
+<0042> <0042>
+endbfchar
+endcmap
+CMapName currentdict /CMap defineresource pop
+end
+end`
+	toUnicode, err := core.MakeStream([]byte(cmap), core.NewRawEncoder())
+	if err != nil {
+		t.Fatalf("MakeStream failed. err=%v", err)
+	}
+	cidSystemInfo := core.MakeDict()
+	cidSystemInfo.Set("Registry", core.MakeString("Adobe"))
+	cidSystemInfo.Set("Ordering", core.MakeString("Identity"))
+	cidSystemInfo.Set("Supplement", core.MakeInteger(0))
+	descendant := core.MakeDict()
+	descendant.Set("Type", core.MakeName("Font"))
+	descendant.Set("Subtype", core.MakeName("CIDFontType2"))
+	descendant.Set("BaseFont", core.MakeName("ABCDEF+Broken"))
+	descendant.Set("CIDSystemInfo", cidSystemInfo)
+	descendant.Set("DW", core.MakeInteger(600))
+	fontDict := core.MakeDict()
+	fontDict.Set("Type", core.MakeName("Font"))
+	fontDict.Set("Subtype", core.MakeName("Type0"))
+	fontDict.Set("BaseFont", core.MakeName("ABCDEF+Broken"))
+	fontDict.Set("Encoding", core.MakeName("Identity-H"))
+	fontDict.Set("DescendantFonts", core.MakeArray(descendant))
+	fontDict.Set("ToUnicode", toUnicode)
+	resources := fragmentResources()
+	resources.SetFontByName("Broken", fontDict)
+	contents := `BT /Broken 10 Tf 10 700 Td <00410042> Tj ET`
+
+	// The override maps <0041> and leaves <0042> to the font's CMap.
+	override := `/CIDInit /ProcSet findresource begin
+12 dict begin
+begincmap
+/CMapName /Fixed def
+/CMapType 2 def
+1 begincodespacerange
+<0000> <FFFF>
+endcodespacerange
+1 beginbfchar
+<0041> <0041>
+endbfchar
+endcmap
+CMapName currentdict /CMap defineresource pop
+end
+end`
+	e := NewFromContents(contents, resources, fragmentMediaBox)
+	for _, test := range []struct {
+		fontName string
+		data     []byte
+		expected string
+	}{
+		{"", nil, "XB"},
+		{"Broken", []byte(override), "AB"},
+		{"Other", []byte(override), "AB"},
+		{"ABCDEF+Broken", nil, "XB"},
+	} {
+		if test.fontName != "" {
+			if err := e.SetCMapOverride(test.fontName, test.data); err != nil {
+				t.Fatalf("SetCMapOverride failed. fontName=%q err=%v", test.fontName, err)
+			}
+		}
+		pt, _, _, err := e.ExtractPageText()
+		if err != nil {
+			t.Fatalf("ExtractPageText failed. err=%v", err)
+		}
+		if text := pt.Text(); text != test.expected {
+			t.Fatalf("fontName=%q: text=%q expected %q", test.fontName, text, test.expected)
+		}
+	}
+}
+
+// TestDocumentCMapOverride checks that DocumentExtractor.SetCMapOverride changes the text of all
+// pages, including pages that were cached before the override was set.
+func TestDocumentCMapOverride(t *testing.T) {
+	override := `/CIDInit /ProcSet findresource begin
+12 dict begin
+begincmap
+/CMapName /Fixed def
+/CMapType 2 def
+1 begincodespacerange
+<00> <FF>
+endcodespacerange
+1 beginbfchar
+<50> <0051>
+endbfchar
+endcmap
+CMapName currentdict /CMap defineresource pop
+end
+end`
+	d := NewDocumentExtractor(testDocument(t, 2), nil)
+	d.SetPageCacheSize(2)
+	for _, test := range []struct {
+		data     []byte
+		expected string
+	}{
+		{nil, "Page"},
+		{[]byte(override), "Qage"},
+		{nil, "Page"},
+	} {
+		if err := d.SetCMapOverride("Helvetica", test.data); err != nil {
+			t.Fatalf("SetCMapOverride failed. err=%v", err)
+		}
+		for pageNum := 1; pageNum <= 2; pageNum++ {
+			pt, err := d.ExtractPageText(pageNum)
+			if err != nil {
+				t.Fatalf("ExtractPageText failed. err=%v", err)
+			}
+			expected := fmt.Sprintf("%s %d", test.expected, pageNum)
+			if !strings.Contains(pt.Text(), expected) {
+				t.Fatalf("page %d: text=%q doesn't contain %q", pageNum, pt.Text(), expected)
+			}
+		}
+	}
+}
+
+// TestCMapOverrideCIDs checks that a CMap override splits strings into codes with its codespace
+// ranges, that the CIDs it maps the codes to give the glyph widths and that the CIDs are decoded
+// with the predefined Unicode CMap of its character collection.
+func TestCMapOverrideCIDs(t *testing.T) {
+	cidSystemInfo := core.MakeDict()
+	cidSystemInfo.Set("Registry", core.MakeString("Adobe"))
+	cidSystemInfo.Set("Ordering", core.MakeString("Japan1"))
+	cidSystemInfo.Set("Supplement", core.MakeInteger(0))
+	descendant := core.MakeDict()
+	descendant.Set("Type", core.MakeName("Font"))
+	descendant.Set("Subtype", core.MakeName("CIDFontType0"))
+	descendant.Set("BaseFont", core.MakeName("Broken"))
+	descendant.Set("CIDSystemInfo", cidSystemInfo)
+	descendant.Set("DW", core.MakeInteger(600))
+	// CIDs 34 and 35 are "A" and "B" in Adobe-Japan1.
+	descendant.Set("W", core.MakeArray(core.MakeInteger(34),
+		core.MakeArray(core.MakeInteger(250), core.MakeInteger(250))))
+	fontDict := core.MakeDict()
+	fontDict.Set("Type", core.MakeName("Font"))
+	fontDict.Set("Subtype", core.MakeName("Type0"))
+	fontDict.Set("BaseFont", core.MakeName("Broken"))
+	fontDict.Set("Encoding", core.MakeName("Identity-H"))
+	fontDict.Set("DescendantFonts", core.MakeArray(descendant))
+	resources := fragmentResources()
+	resources.SetFontByName("Broken", fontDict)
+	// The font's Identity-H encoding reads <4142> as one 2-byte code but the string has two
+	// 1-byte codes.
+	contents := `BT /Broken 10 Tf 10 700 Td <4142> Tj ET`
+
+	override := `/CIDInit /ProcSet findresource begin
+12 dict begin
+begincmap
+/CIDSystemInfo << /Registry (Adobe) /Ordering (Japan1) /Supplement 0 >> def
+/CMapName /Fixed def
+/CMapType 1 def
+1 begincodespacerange
+<00> <FF>
+endcodespacerange
+1 begincidrange
+<41> <42> 34
+endcidrange
+endcmap
+CMapName currentdict /CMap defineresource pop
+end
+end`
+	e := NewFromContents(contents, resources, fragmentMediaBox)
+	if err := e.SetCMapOverride("Broken", []byte(override)); err != nil {
+		t.Fatalf("SetCMapOverride failed. err=%v", err)
+	}
+	pt, _, _, err := e.ExtractPageText()
+	if err != nil {
+		t.Fatalf("ExtractPageText failed. err=%v", err)
+	}
+	if text := pt.Text(); text != "AB" {
+		t.Fatalf("text=%q expected %q", text, "AB")
+	}
+	marks := pt.Marks().Elements()
+	if len(marks) != 2 {
+		t.Fatalf("%d marks expected 2", len(marks))
+	}
+	// The glyphs are 250 units wide, not the font's default 600.
+	if x := marks[1].BBox.Llx; math.Abs(x-12.5) > 0.01 {
+		t.Fatalf("second mark starts at x=%.2f expected 12.50", x)
+	}
+}
@@ -0,0 +1,172 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package extractor
+
+import (
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+// Columns returns the text of each column of text on the page, ordered left to right. Each
+// column's text is its lines from top to bottom, joined by the line separator. A page with no
+// detected columns is returned as a single column.
+// Columns are separated by vertical gutters: bands of the page with no text on nearly all lines.
+// Lines that span several columns, such as titles, are split at the gutters.
+// ExtractOptions.ColumnGutterWidth sets the minimum width of gutters.
+func (pt PageText) Columns() []string {
+	lines := pt.viewLines()
+	gutters := columnGutters(lines, pt.options.ColumnGutterWidth)
+	lineSep := pt.options.lineSeparator()
+	columns := make([][]string, len(gutters)+1)
+	for _, marks := range lines {
+		parts := make([][]string, len(columns))
+		col := 0
+		for _, tm := range marks {
+			// Meta marks have no position so they stay in the column of the preceding mark.
+			if !tm.Meta {
+				col = gutterIndex(gutters, (tm.BBox.Llx+tm.BBox.Urx)/2)
+			}
+			parts[col] = append(parts[col], tm.Text)
+		}
+		for i, part := range parts {
+			if text := strings.TrimSpace(strings.Join(part, "")); text != "" {
+				columns[i] = append(columns[i], text)
+			}
+		}
+	}
+	var texts []string
+	for _, col := range columns {
+		if len(col) > 0 {
+			texts = append(texts, strings.Join(col, lineSep))
+		}
+	}
+	return texts
+}
+
+// ColumnGutters returns the gutters between the columns of text that Columns() splits the page
+// at, ordered left to right. Each gutter is a rectangle that spans the text vertically. This is
+// useful for checking column detection.
+func (pt PageText) ColumnGutters() []model.PdfRectangle {
+	lines := pt.viewLines()
+	gutters := columnGutters(lines, pt.options.ColumnGutterWidth)
+	if len(gutters) == 0 {
+		return nil
+	}
+	var marks []TextMark
+	for _, l := range lines {
+		marks = append(marks, l...)
+	}
+	bbox, _ := marksBBox(marks)
+	rects := make([]model.PdfRectangle, len(gutters))
+	for i, g := range gutters {
+		rects[i] = model.PdfRectangle{Llx: g.llx, Lly: bbox.Lly, Urx: g.urx, Ury: bbox.Ury}
+	}
+	return rects
+}
+
+const (
+	// colGutterWidth is the default minimum width of a column gutter as a fraction of the page's
+	// dominant font size.
+	colGutterWidth = 1.5
+	// colSpanFraction is the maximum fraction of the lines on the page that may cross a gutter.
+	// This allows for titles and headings that span several columns.
+	colSpanFraction = 0.1
+	// colMinLines is the minimum number of lines needed to detect columns.
+	colMinLines = 3
+)
+
+// gutter is a vertical band of the page between two columns of text.
+type gutter struct {
+	llx, urx float64 // The left and right edges of the gutter.
+}
+
+// gutterIndex returns the index of the column containing `x` for columns separated by `gutters`.
+func gutterIndex(gutters []gutter, x float64) int {
+	return sort.Search(len(gutters), func(i int) bool { return x < gutters[i].urx })
+}
+
+// columnGutters returns the gutters between the columns of text in `lines`, ordered left to right.
+// A gutter is a horizontal range at least `minWidth` points wide that has text on both sides and
+// is crossed by text in at most colSpanFraction of `lines`. If `minWidth` is not positive,
+// colGutterWidth font sizes is used.
+func columnGutters(lines [][]TextMark, minWidth float64) []gutter {
+	var marks []TextMark
+	for _, l := range lines {
+		marks = append(marks, l...)
+	}
+	fontSize := dominantFontSize(marks)
+	if fontSize <= 0 {
+		return nil
+	}
+	if minWidth <= 0 {
+		minWidth = colGutterWidth * fontSize
+	}
+
+	// Each line contributes the horizontal ranges of its runs of text. Runs are split at gaps at
+	// least as wide as a gutter so that gaps between words don't break up runs.
+	type edge struct {
+		x     float64
+		delta int
+	}
+	var edges []edge
+	numLines := 0
+	minX, maxX := math.MaxFloat64, -math.MaxFloat64
+	for _, l := range lines {
+		var runs []gutter
+		for _, tm := range l {
+			if tm.Meta || isTextSpace(tm.Text) {
+				continue
+			}
+			llx, urx := tm.BBox.Llx, tm.BBox.Urx
+			if n := len(runs); n > 0 && llx-runs[n-1].urx < minWidth {
+				runs[n-1].llx = math.Min(runs[n-1].llx, llx)
+				runs[n-1].urx = math.Max(runs[n-1].urx, urx)
+				continue
+			}
+			runs = append(runs, gutter{llx: llx, urx: urx})
+		}
+		if len(runs) == 0 {
+			continue
+		}
+		numLines++
+		for _, r := range runs {
+			edges = append(edges, edge{r.llx, 1}, edge{r.urx, -1})
+			minX = math.Min(minX, r.llx)
+			maxX = math.Max(maxX, r.urx)
+		}
+	}
+	if numLines < colMinLines {
+		return nil
+	}
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].x != edges[j].x {
+			return edges[i].x < edges[j].x
+		}
+		return edges[i].delta > edges[j].delta
+	})
+
+	// Sweep left to right, tracking the number of runs covering each x.
+	maxCover := int(colSpanFraction * float64(numLines))
+	var gutters []gutter
+	cover := 0
+	start := math.NaN()
+	for _, e := range edges {
+		cover += e.delta
+		switch {
+		case cover <= maxCover && math.IsNaN(start):
+			start = e.x
+		case cover > maxCover && !math.IsNaN(start):
+			if start > minX && e.x < maxX && e.x-start >= minWidth {
+				gutters = append(gutters, gutter{llx: start, urx: e.x})
+			}
+			start = math.NaN()
+		}
+	}
+	return gutters
+}
@@ -0,0 +1,102 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package extractor
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// TestColumns checks that PageText.Columns() splits a two column page into its columns.
+func TestColumns(t *testing.T) {
+	var b strings.Builder
+	b.WriteString("BT /UniDocCourier 10 Tf\n")
+	// The title crosses the gutter so there must be enough lines to allow for it.
+	b.WriteString("1 0 0 1 100 750 Tm (A title that spans both columns) Tj\n")
+	for i := 0; i < 10; i++ {
+		y := 700 - 20*i
+		fmt.Fprintf(&b, "1 0 0 1 10 %d Tm (Left line %d) Tj\n", y, i)
+		fmt.Fprintf(&b, "1 0 0 1 300 %d Tm (Right line %d) Tj\n", y, i)
+	}
+	b.WriteString("ET")
+	pt := fragmentPageText(t, b.String())
+
+	columns := pt.Columns()
+	if len(columns) != 2 {
+		t.Fatalf("%d columns expected 2. columns=%q", len(columns), columns)
+	}
+	for i, side := range []string{"Left", "Right"} {
+		lines := strings.Split(columns[i], "\n")
+		if i == 0 {
+			// The title is centered over the left column.
+			if lines[0] != "A title that spans both columns" {
+				t.Fatalf("column 0: title=%q", lines[0])
+			}
+			lines = lines[1:]
+		}
+		if len(lines) != 10 {
+			t.Fatalf("column %d: %d lines expected 10. column=%q", i, len(lines), columns[i])
+		}
+		for j, line := range lines {
+			expected := fmt.Sprintf("%s line %d", side, j)
+			if line != expected {
+				t.Fatalf("column %d line %d: got %q expected %q", i, j, line, expected)
+			}
+		}
+	}
+
+	single := fragmentPageText(t, `BT /UniDocCourier 10 Tf 10 700 Td (One) Tj 0 -20 Td (Two) Tj
+		0 -20 Td (Three words here) Tj ET`)
+	if columns := single.Columns(); len(columns) != 1 || columns[0] != "One\nTwo\nThree words here" {
+		t.Fatalf("single column page: columns=%q", columns)
+	}
+}
+
+// TestColumnGutterWidth checks that ExtractOptions.ColumnGutterWidth sets the minimum width of the
+// gutters that PageText.Columns() splits pages at, and that PageText.ColumnGutters() returns them.
+func TestColumnGutterWidth(t *testing.T) {
+	var b strings.Builder
+	b.WriteString("BT /UniDocCourier 10 Tf\n")
+	// The left lines end at x=76 so the gutter is 12 points wide.
+	for i := 0; i < 5; i++ {
+		y := 700 - 20*i
+		fmt.Fprintf(&b, "1 0 0 1 10 %d Tm (Left line %d) Tj\n", y, i)
+		fmt.Fprintf(&b, "1 0 0 1 88 %d Tm (Right line %d) Tj\n", y, i)
+	}
+	b.WriteString("ET")
+	contents := b.String()
+
+	for _, test := range []struct {
+		width      float64
+		numColumns int
+	}{
+		{0, 1},
+		{10, 2},
+		{15, 1},
+	} {
+		e := Extractor{resources: fragmentResources(), contents: contents,
+			options: ExtractOptions{ColumnGutterWidth: test.width}}
+		pt, _, _, err := e.ExtractPageText()
+		if err != nil {
+			t.Fatalf("ExtractPageText failed. err=%v", err)
+		}
+		columns := pt.Columns()
+		if len(columns) != test.numColumns {
+			t.Fatalf("ColumnGutterWidth=%g: %d columns expected %d. columns=%q", test.width,
+				len(columns), test.numColumns, columns)
+		}
+		gutters := pt.ColumnGutters()
+		if len(gutters) != test.numColumns-1 {
+			t.Fatalf("ColumnGutterWidth=%g: gutters=%v", test.width, gutters)
+		}
+		for _, g := range gutters {
+			if g.Llx != 76 || g.Urx != 88 || g.Lly != 620 || g.Ury != 710 {
+				t.Fatalf("ColumnGutterWidth=%g: incorrect gutter %v", test.width, g)
+			}
+		}
+	}
+}
@@ -0,0 +1,107 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package extractor
+
+import (
+	"sort"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/unidoc/unipdf/v3/internal/transform"
+)
+
+const (
+	// dropCapMinLines is the minimum height of a drop cap as a multiple of the height of the body
+	// text it is next to.
+	dropCapMinLines = 2.0
+	// dropCapMaxGap is the maximum gap between a drop cap and the text to its right as a
+	// multiple of the height of the body text.
+	dropCapMaxGap = 2.0
+	// dropCapTol is the tolerance, as a fraction of the height of the body text, for the
+	// baselines of the lines next to a drop cap.
+	dropCapTol = 0.5
+)
+
+// attachDropCaps finds the drop caps in `pt`.marks and moves them to the start of the first line
+// of the text they are next to. A drop cap is a large initial letter at the start of a paragraph.
+// Its baseline is usually the baseline of the last of the several lines of body text that it
+// spans, so it would otherwise be put at the start of that line rather than the first line.
+// A drop cap is a single letter that is at least dropCapMinLines times the height of the body
+// text with the start of at least 2 lines of body text just to its right. Only the positions that
+// the marks are ordered by are changed. The bounding boxes are not changed.
+func (pt *PageText) attachDropCaps() {
+	heights := make(map[int][]float64)
+	for _, tm := range pt.marks {
+		if !isTextSpace(tm.text) {
+			heights[tm.orient] = append(heights[tm.orient], tm.height)
+		}
+	}
+	bodyHeights := make(map[int]float64, len(heights))
+	for orient, hs := range heights {
+		sort.Float64s(hs)
+		bodyHeights[orient] = hs[len(hs)/2]
+	}
+
+	for i := range pt.marks {
+		tm := &pt.marks[i]
+		body := bodyHeights[tm.orient]
+		if body <= 0 || tm.height < dropCapMinLines*body || !isDropCapText(tm.text) {
+			continue
+		}
+		lo, hi := tm.orientedStart.Y-dropCapTol*body, tm.orientedStart.Y+tm.height-body
+		// lines are the lines of text to the right of `tm`.
+		var lines []dropCapLine
+		for j, t := range pt.marks {
+			if j == i || t.orient != tm.orient || t.height >= dropCapMinLines*body ||
+				t.orientedStart.Y < lo || t.orientedStart.Y > hi ||
+				t.orientedStart.X < tm.orientedEnd.X-dropCapTol*body ||
+				t.orientedStart.X > tm.orientedEnd.X+dropCapMaxGap*body {
+				continue
+			}
+			lines = addDropCapLine(lines, t.orientedStart, dropCapTol*body)
+		}
+		if len(lines) < 2 {
+			continue
+		}
+		top := lines[0]
+		for _, l := range lines[1:] {
+			if l.y > top.y {
+				top = l
+			}
+		}
+		tm.orientedStart.Y, tm.orientedEnd.Y = top.y, top.y
+		if top.x > tm.orientedStart.X {
+			// The drop cap is the first letter of the first word of the line.
+			tm.orientedEnd.X = top.x
+		}
+	}
+}
+
+// dropCapLine is a line of text to the right of a drop cap.
+type dropCapLine struct {
+	y float64 // Baseline of the line.
+	x float64 // Start of the leftmost text in the line.
+}
+
+// addDropCapLine adds text that starts at `start` to the line in `lines` whose baseline is within
+// `tol` of it, or to a new line if there is no such line, and returns the updated lines.
+func addDropCapLine(lines []dropCapLine, start transform.Point, tol float64) []dropCapLine {
+	for k, l := range lines {
+		if l.y-tol <= start.Y && start.Y <= l.y+tol {
+			if start.X < l.x {
+				lines[k].x = start.X
+			}
+			return lines
+		}
+	}
+	return append(lines, dropCapLine{y: start.Y, x: start.X})
+}
+
+// isDropCapText returns true if `text` is a single letter or digit.
+func isDropCapText(text string) bool {
+	r, size := utf8.DecodeRuneInString(text)
+	return size > 0 && size == len(text) && (unicode.IsLetter(r) || unicode.IsDigit(r))
+}
@@ -0,0 +1,38 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package extractor
+
+import (
+	"testing"
+)
+
+// TestDropCaps checks that a drop cap is extracted as the first letter of the first line of the
+// paragraph it starts, and that a large letter next to a single line is not treated as one.
+func TestDropCaps(t *testing.T) {
+	for _, test := range []struct {
+		contents string
+		expected string
+	}{
+		{`
+        BT /UniDocHelvetica 44 Tf 10 672 Td (T) Tj ET
+        BT /UniDocHelvetica 10 Tf 14 TL 38 700 Td
+        (he quick brown) Tj T* (fox jumps over) Tj T* (the lazy dog and) Tj
+        -28 -14 Td (keeps on running.) Tj ET`,
+			"The quick brown\nfox jumps over\nthe lazy dog and\nkeeps on running.",
+		},
+		{`
+        BT /UniDocHelvetica 44 Tf 10 700 Td (A) Tj ET
+        BT /UniDocHelvetica 10 Tf 50 700 Td (grade) Tj ET
+        BT /UniDocHelvetica 10 Tf 10 660 Td (Next paragraph) Tj ET`,
+			"A grade\nNext paragraph",
+		},
+	} {
+		pt := fragmentPageText(t, test.contents)
+		if text := pt.Text(); text != test.expected {
+			t.Fatalf("text=%q expected %q", text, test.expected)
+		}
+	}
+}
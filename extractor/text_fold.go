@@ -0,0 +1,61 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package extractor
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// FoldedText returns a searchable form of the extracted text of `pt`. It is lower case, has
+// diacritics removed and has compatibility characters, such as full width letters and ligatures,
+// replaced by their standard equivalents. e.g. "Ｃafé ﬁle" is folded to "cafe file".
+// This is a lossy form of the text for indexing and searching. FoldedMarks maps substrings of
+// the folded text back to the page.
+func (pt PageText) FoldedText() string {
+	var b strings.Builder
+	for _, tm := range pt.viewMarks {
+		b.WriteString(foldText(tm.Text))
+	}
+	return b.String()
+}
+
+// FoldedMarks returns the TextMarks of `pt` with their Text folded as in FoldedText and their
+// Offsets set to the offsets of the folded texts in FoldedText(). The bounding boxes and other
+// fields are unchanged so, for example, the bounding box of a match `start`:`end` in FoldedText()
+// can be found with
+//
+//	marks, err := pt.FoldedMarks().RangeOffset(start, end)
+//	// handle errors
+//	bbox, ok := marks.BBox()
+func (pt PageText) FoldedMarks() *TextMarkArray {
+	marks := make([]TextMark, len(pt.viewMarks))
+	offset := 0
+	for i, tm := range pt.viewMarks {
+		tm.Text = foldText(tm.Text)
+		tm.Offset = offset
+		offset += len(tm.Text)
+		marks[i] = tm
+	}
+	return &TextMarkArray{marks: marks}
+}
+
+// foldText returns `text` folded for searching. It is converted to lower case, compatibility
+// characters are decomposed and combining marks are removed. Diacritics that are drawn as
+// separate glyphs are folded to empty strings.
+func foldText(text string) string {
+	decomposed := norm.NFKD.String(text)
+	var b strings.Builder
+	for _, r := range decomposed {
+		if unicode.Is(unicode.Mn, r) {
+			continue
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
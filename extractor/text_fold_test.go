@@ -0,0 +1,76 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package extractor
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/unidoc/unipdf/v3/core"
+)
+
+// TestFoldedText checks that PageText.FoldedText() folds case, diacritics and compatibility
+// characters and that FoldedMarks() maps the folded text back to the marks.
+func TestFoldedText(t *testing.T) {
+	// A font that maps A-F to "Ｃ", "a", "f", "É", "ﬁ", "n".
+	cmap := `/CIDInit /ProcSet findresource begin
+12 dict begin
+begincmap
+/CMapName /Fold def
+/CMapType 2 def
+1 begincodespacerange
+<00> <FF>
+endcodespacerange
+6 beginbfchar
+<41> <FF23>
+<42> <0061>
+<43> <0066>
+<44> <00C9>
+<45> <FB01>
+<46> <006E>
+endbfchar
+endcmap
+CMapName currentdict /CMap defineresource pop
+end
+end`
+	toUnicode, err := core.MakeStream([]byte(cmap), core.NewRawEncoder())
+	if err != nil {
+		t.Fatalf("MakeStream failed. err=%v", err)
+	}
+	fontDict := core.MakeDict()
+	fontDict.Set("Type", core.MakeName("Font"))
+	fontDict.Set("Subtype", core.MakeName("TrueType"))
+	fontDict.Set("BaseFont", core.MakeName("Fold"))
+	fontDict.Set("FirstChar", core.MakeInteger(65))
+	fontDict.Set("LastChar", core.MakeInteger(70))
+	fontDict.Set("Widths", core.MakeArrayFromIntegers([]int{600, 600, 600, 600, 600, 600}))
+	fontDict.Set("ToUnicode", toUnicode)
+	resources := fragmentResources()
+	resources.SetFontByName("Fold", fontDict)
+	contents := `BT /Fold 10 Tf 10 700 Td (ABCD) Tj 30 0 Td (EF) Tj /UniDocCourier 10 Tf (D) Tj ET`
+
+	e := NewFromContents(contents, resources, fragmentMediaBox)
+	pt, _, _, err := e.ExtractPageText()
+	if err != nil {
+		t.Fatalf("ExtractPageText failed. err=%v", err)
+	}
+	folded := pt.FoldedText()
+	if folded != "cafe find" {
+		t.Fatalf("FoldedText=%q text=%q", folded, pt.Text())
+	}
+
+	// "find" is drawn from x=40 to x=58.
+	start := strings.Index(folded, "find")
+	marks, err := pt.FoldedMarks().RangeOffset(start, start+len("find"))
+	if err != nil {
+		t.Fatalf("RangeOffset failed. err=%v", err)
+	}
+	bbox, ok := marks.BBox()
+	if !ok || math.Abs(bbox.Llx-40) > 0.01 || math.Abs(bbox.Urx-58) > 0.01 {
+		t.Fatalf("incorrect bbox=%+v", bbox)
+	}
+}
@@ -0,0 +1,77 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package extractor
+
+import (
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+// FontDecodeStats are the numbers of characters shown in a font on a page and the number of them
+// that were not decoded. They are the per font breakdown of the `numChars` and `numMisses` returned
+// by Extractor.ExtractPageText and can be used to find fonts that extract poorly.
+type FontDecodeStats struct {
+	Font      *model.PdfFont
+	NumChars  int // Number of characters shown.
+	NumMisses int // Number of characters that were not decoded.
+	// NumMetricMisses is the number of characters that the font has no metrics for. Their widths
+	// are estimated, so the positions of the text after them may be inaccurate. They are counted
+	// separately from NumMisses because they may have been decoded correctly.
+	NumMetricMisses int
+	// fontObj is the object that Font was loaded from. It is nil for the default font.
+	fontObj core.PdfObject
+	// isDefault is true if Font is the default font used because no font was set.
+	isDefault bool
+}
+
+// DecodedRatio returns the fraction of the characters in `s` that were decoded successfully. It is
+// 1 if no characters were shown.
+func (s FontDecodeStats) DecodedRatio() float64 {
+	if s.NumChars == 0 {
+		return 1
+	}
+	return maxFloat(0, float64(s.NumChars-s.NumMisses)/float64(s.NumChars))
+}
+
+// FontDecodeStats returns the decoding statistics of each font used on the page of `pt` in the
+// order that the fonts were first used.
+func (pt PageText) FontDecodeStats() []FontDecodeStats {
+	stats := make([]FontDecodeStats, len(pt.fontStats))
+	copy(stats, pt.fontStats)
+	return stats
+}
+
+// addFontDecodeStats returns `stats` with the counts in `add` added to the counts for the font of
+// `add`. Fonts are identified by the objects they were loaded from because a font that is evicted
+// from the font cache is loaded again as a new PdfFont. Fonts that weren't loaded from an object,
+// such as the default font, are identified by their PdfFont.
+func addFontDecodeStats(stats []FontDecodeStats, add FontDecodeStats) []FontDecodeStats {
+	for i := range stats {
+		if sameStatsFont(stats[i], add) {
+			stats[i].NumChars += add.NumChars
+			stats[i].NumMisses += add.NumMisses
+			stats[i].NumMetricMisses += add.NumMetricMisses
+			return stats
+		}
+	}
+	return append(stats, add)
+}
+
+// sameStatsFont returns true if `a` and `b` are the statistics of the same font.
+func sameStatsFont(a, b FontDecodeStats) bool {
+	if a.fontObj != nil || b.fontObj != nil {
+		return a.fontObj == b.fontObj
+	}
+	return a.Font == b.Font
+}
+
+// mergeFontDecodeStats returns `stats` with the counts in `other` added.
+func mergeFontDecodeStats(stats, other []FontDecodeStats) []FontDecodeStats {
+	for _, s := range other {
+		stats = addFontDecodeStats(stats, s)
+	}
+	return stats
+}
@@ -0,0 +1,121 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package extractor
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+// TestFontDecodeStats checks that PageText.FontDecodeStats() counts the characters and misses of
+// each font, including those in form XObjects.
+func TestFontDecodeStats(t *testing.T) {
+	cmap := `/CIDInit /ProcSet findresource begin
+12 dict begin
+begincmap
+/CMapName /Partial def
+/CMapType 2 def
+1 begincodespacerange
+<00> <FF>
+endcodespacerange
+1 beginbfchar
+<41> <0041>
+endbfchar
+endcmap
+CMapName currentdict /CMap defineresource pop
+end
+end`
+	toUnicode, err := core.MakeStream([]byte(cmap), core.NewRawEncoder())
+	if err != nil {
+		t.Fatalf("MakeStream failed. err=%v", err)
+	}
+	// A font with a ToUnicode CMap that only maps "A", no encoding and no width for code 0x01.
+	fontDict := core.MakeDict()
+	fontDict.Set("Type", core.MakeName("Font"))
+	fontDict.Set("Subtype", core.MakeName("TrueType"))
+	fontDict.Set("BaseFont", core.MakeName("Partial"))
+	fontDict.Set("FirstChar", core.MakeInteger(65))
+	fontDict.Set("LastChar", core.MakeInteger(65))
+	fontDict.Set("Widths", core.MakeArrayFromIntegers([]int{600}))
+	fontDict.Set("ToUnicode", toUnicode)
+	resources := fragmentResources()
+	resources.SetFontByName("Partial", fontDict)
+
+	xform := model.NewXObjectForm()
+	xform.BBox = core.MakeArrayFromFloats([]float64{0, 0, 612, 792})
+	err = xform.SetContentStream([]byte(`BT /Partial 10 Tf 10 600 Td <4101> Tj ET`),
+		core.NewRawEncoder())
+	if err != nil {
+		t.Fatalf("SetContentStream failed. err=%v", err)
+	}
+	if err := resources.SetXObjectFormByName("Fm1", xform); err != nil {
+		t.Fatalf("SetXObjectFormByName failed. err=%v", err)
+	}
+	contents := `BT /Partial 10 Tf 10 700 Td <41> Tj /UniDocCourier 10 Tf (BC) Tj ET /Fm1 Do`
+
+	e := NewFromContents(contents, resources, fragmentMediaBox)
+	pt, numChars, numMisses, err := e.ExtractPageText()
+	if err != nil {
+		t.Fatalf("ExtractPageText failed. err=%v", err)
+	}
+	stats := pt.FontDecodeStats()
+	if len(stats) != 2 {
+		t.Fatalf("%d fonts expected 2. stats=%+v", len(stats), stats)
+	}
+	// Code 0x01 is neither decoded nor has a width. It is counted once as a miss and once as a
+	// metric miss.
+	partial, courier := stats[0], stats[1]
+	if partial.NumChars != 3 || partial.NumMisses != 1 || partial.NumMetricMisses != 1 ||
+		math.Abs(partial.DecodedRatio()-2.0/3) > 1e-6 {
+		t.Fatalf("incorrect stats for Partial font. %+v", partial)
+	}
+	if courier.NumChars != 2 || courier.NumMisses != 0 || courier.DecodedRatio() != 1 {
+		t.Fatalf("incorrect stats for Courier font. %+v", courier)
+	}
+	if numChars != partial.NumChars+courier.NumChars || numMisses != partial.NumMisses+courier.NumMisses {
+		t.Fatalf("numChars=%d numMisses=%d don't match stats=%+v", numChars, numMisses, stats)
+	}
+}
+
+// TestFontDecodeStatsManyFonts checks that a font that is used again after it has been evicted
+// from the font cache has one FontDecodeStats entry.
+func TestFontDecodeStatsManyFonts(t *testing.T) {
+	const numFonts = maxFontCache + 2
+	resources := fragmentResources()
+	var b strings.Builder
+	b.WriteString("BT 10 700 Td ")
+	for i := 0; i <= numFonts; i++ {
+		name := fmt.Sprintf("F%d", i%numFonts)
+		if i < numFonts {
+			fontDict := core.MakeDict()
+			fontDict.Set("Type", core.MakeName("Font"))
+			fontDict.Set("Subtype", core.MakeName("Type1"))
+			fontDict.Set("BaseFont", core.MakeName("Courier"))
+			resources.SetFontByName(core.PdfObjectName(name), fontDict)
+		}
+		fmt.Fprintf(&b, "/%s 10 Tf (ab) Tj ", name)
+	}
+	b.WriteString("ET")
+
+	e := NewFromContents(b.String(), resources, fragmentMediaBox)
+	pt, _, _, err := e.ExtractPageText()
+	if err != nil {
+		t.Fatalf("ExtractPageText failed. err=%v", err)
+	}
+	stats := pt.FontDecodeStats()
+	if len(stats) != numFonts {
+		t.Fatalf("%d fonts expected %d. stats=%+v", len(stats), numFonts, stats)
+	}
+	// The first font is used at the start and the end of the page.
+	if stats[0].NumChars != 4 {
+		t.Fatalf("NumChars=%d expected 4. stats=%+v", stats[0].NumChars, stats[0])
+	}
+}
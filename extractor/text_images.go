@@ -0,0 +1,43 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package extractor
+
+import (
+	"fmt"
+
+	"github.com/unidoc/unipdf/v3/internal/transform"
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+// ImageRegion is the placement of an image XObject drawn on a page.
+type ImageRegion struct {
+	// Name is the name of the image XObject in the resources of the content stream that drew it.
+	// Images drawn in form XObjects are named in the forms' resources.
+	Name string
+	// BBox is the bounding box of the image on the page.
+	BBox model.PdfRectangle
+}
+
+// String returns a string describing `r`.
+func (r ImageRegion) String() string {
+	b := r.BBox
+	return fmt.Sprintf("{ImageRegion: %q (%5.1f, %5.1f) (%5.1f, %5.1f)}", r.Name, b.Llx, b.Lly,
+		b.Urx, b.Ury)
+}
+
+// Images returns the placements of the image XObjects drawn on the page of `pt`, including those
+// drawn in form XObjects, in the order they were drawn. This gives the positions of figures
+// relative to the text without decoding the images. ExtractPageImages returns the images'
+// pixels. Inline images are not included.
+func (pt PageText) Images() []ImageRegion {
+	return pt.images
+}
+
+// imageBBox returns the bounding box of an image drawn with current transformation matrix `ctm`.
+// Images are drawn in the unit square of user space.
+func imageBBox(ctm transform.Matrix) model.PdfRectangle {
+	return transformRect(ctm, model.PdfRectangle{Llx: 0, Lly: 0, Urx: 1, Ury: 1})
+}
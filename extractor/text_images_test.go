@@ -0,0 +1,77 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package extractor
+
+import (
+	"math"
+	"testing"
+
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+// TestImages checks that PageText.Images() returns the names and positions of the image XObjects
+// drawn on a page and in its forms.
+func TestImages(t *testing.T) {
+	newImage := func() *core.PdfObjectStream {
+		image, err := core.MakeStream([]byte{0}, core.NewRawEncoder())
+		if err != nil {
+			t.Fatalf("MakeStream failed. err=%v", err)
+		}
+		image.Set("Type", core.MakeName("XObject"))
+		image.Set("Subtype", core.MakeName("Image"))
+		image.Set("Width", core.MakeInteger(1))
+		image.Set("Height", core.MakeInteger(1))
+		image.Set("ColorSpace", core.MakeName("DeviceGray"))
+		image.Set("BitsPerComponent", core.MakeInteger(8))
+		return image
+	}
+	formResources := model.NewPdfPageResources()
+	if err := formResources.SetXObjectByName("Logo", newImage()); err != nil {
+		t.Fatalf("SetXObjectByName failed. err=%v", err)
+	}
+	xform := model.NewXObjectForm()
+	xform.BBox = core.MakeArrayFromFloats([]float64{0, 0, 612, 792})
+	xform.Matrix = core.MakeArrayFromFloats([]float64{1, 0, 0, 1, 0, 50})
+	xform.Resources = formResources
+	err := xform.SetContentStream([]byte(`q 40 0 0 20 500 700 cm /Logo Do Q`), core.NewRawEncoder())
+	if err != nil {
+		t.Fatalf("SetContentStream failed. err=%v", err)
+	}
+	resources := fragmentResources()
+	if err := resources.SetXObjectByName("Im1", newImage()); err != nil {
+		t.Fatalf("SetXObjectByName failed. err=%v", err)
+	}
+	if err := resources.SetXObjectFormByName("Fm1", xform); err != nil {
+		t.Fatalf("SetXObjectFormByName failed. err=%v", err)
+	}
+	contents := `
+        BT /UniDocCourier 10 Tf 10 700 Td (Figure 1) Tj ET
+        q 200 0 0 100 10 580 cm /Im1 Do Q
+        /Fm1 Do`
+
+	e := NewFromContents(contents, resources, fragmentMediaBox)
+	pt, _, _, err := e.ExtractPageText()
+	if err != nil {
+		t.Fatalf("ExtractPageText failed. err=%v", err)
+	}
+	expected := []ImageRegion{
+		{"Im1", model.PdfRectangle{Llx: 10, Lly: 580, Urx: 210, Ury: 680}},
+		{"Logo", model.PdfRectangle{Llx: 500, Lly: 750, Urx: 540, Ury: 770}},
+	}
+	images := pt.Images()
+	if len(images) != len(expected) {
+		t.Fatalf("%d images expected %d. images=%v", len(images), len(expected), images)
+	}
+	for i, img := range images {
+		exp := expected[i]
+		b, eb := img.BBox, exp.BBox
+		if img.Name != exp.Name || math.Abs(b.Llx-eb.Llx) > 0.01 || math.Abs(b.Lly-eb.Lly) > 0.01 ||
+			math.Abs(b.Urx-eb.Urx) > 0.01 || math.Abs(b.Ury-eb.Ury) > 0.01 {
+			t.Fatalf("image %d: %s expected %s", i, img, exp)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package extractor
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+// KeyValue is a label and its value, such as "Invoice number: 1234", in a form or metadata sheet.
+type KeyValue struct {
+	// Key is the label without a trailing colon, e.g. "Invoice number".
+	Key string
+	// Value is the text of the value, e.g. "1234".
+	Value string
+	// KeyBBox and ValueBBox are the bounding boxes of the label and the value.
+	KeyBBox, ValueBBox model.PdfRectangle
+}
+
+// String returns a string describing `kv`.
+func (kv KeyValue) String() string {
+	return fmt.Sprintf("{KeyValue: %q=%q}", kv.Key, kv.Value)
+}
+
+// KeyValues returns the label-value pairs on the page of `pt`, in the order of the lines they are
+// on and from left to right.
+// Each line is divided into segments at gaps as wide as column gutters. A label is found in 2
+// ways:
+//   - Inline, as the words of a segment up to and including a word that ends with a colon, e.g.
+//     "Name: John Smith". The value is the rest of the segment.
+//   - In a column, as a segment that ends with a colon or a short segment in a bold font, e.g.
+//     "Name:" or "Name". The value is the next segment on the line.
+//
+// Labels have at most kvMaxKeyWords words.
+func (pt PageText) KeyValues() []KeyValue {
+	var kvs []KeyValue
+	for _, tl := range pt.Lines() {
+		segs := lineSegments(tl)
+		for i := 0; i < len(segs); i++ {
+			seg := segs[i]
+			if k := colonWord(seg); k >= 0 && k < len(seg)-1 {
+				kvs = append(kvs, newKeyValue(seg[:k+1], seg[k+1:]))
+				continue
+			}
+			if i+1 < len(segs) && isLabel(seg) && !isLabel(segs[i+1]) {
+				kvs = append(kvs, newKeyValue(seg, segs[i+1]))
+				i++
+			}
+		}
+	}
+	return kvs
+}
+
+const (
+	// kvMaxKeyWords is the maximum number of words in a label.
+	kvMaxKeyWords = 5
+	// kvSegmentGap is the minimum gap between the segments of a line as a fraction of the line's
+	// font size. This is the default column gutter width.
+	kvSegmentGap = colGutterWidth
+)
+
+// lineSegments returns the words of `tl` divided into segments at gaps at least kvSegmentGap font
+// sizes wide.
+func lineSegments(tl TextLine) [][][]TextMark {
+	var segs [][][]TextMark
+	var seg [][]TextMark
+	lastUrx := 0.0
+	for _, word := range lineWords(tl.Marks.Elements()) {
+		bbox, _ := marksBBox(word)
+		if len(seg) > 0 && bbox.Llx-lastUrx >= kvSegmentGap*tl.FontSize {
+			segs = append(segs, seg)
+			seg = nil
+		}
+		seg = append(seg, word)
+		lastUrx = bbox.Urx
+	}
+	if len(seg) > 0 {
+		segs = append(segs, seg)
+	}
+	return segs
+}
+
+// colonWord returns the index of the first word in the words of segment `seg` that ends with a
+// colon, if it is one of the first kvMaxKeyWords words, or -1 if there is no such word.
+func colonWord(seg [][]TextMark) int {
+	for k, word := range seg {
+		if k >= kvMaxKeyWords {
+			break
+		}
+		if strings.HasSuffix(marksText(word), ":") {
+			return k
+		}
+	}
+	return -1
+}
+
+// isLabel returns true if the words of segment `seg` appear to be a label on their own. That is,
+// if they end with a colon or are in a bold font, and there are at most kvMaxKeyWords of them.
+func isLabel(seg [][]TextMark) bool {
+	if len(seg) > kvMaxKeyWords {
+		return false
+	}
+	if colonWord(seg) == len(seg)-1 {
+		return true
+	}
+	for _, word := range seg {
+		for _, tm := range word {
+			if bold, _ := fontStyle(tm.Font); !bold {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// newKeyValue returns the KeyValue for the label words `key` and value words `value`.
+func newKeyValue(key, value [][]TextMark) KeyValue {
+	var kv KeyValue
+	kv.Key, kv.KeyBBox = segmentText(key)
+	kv.Key = strings.TrimSpace(strings.TrimSuffix(kv.Key, ":"))
+	kv.Value, kv.ValueBBox = segmentText(value)
+	return kv
+}
+
+// segmentText returns the text of the words in `seg`, separated by spaces, and their bounding box.
+func segmentText(seg [][]TextMark) (string, model.PdfRectangle) {
+	texts := make([]string, len(seg))
+	var marks []TextMark
+	for i, word := range seg {
+		texts[i] = marksText(word)
+		marks = append(marks, word...)
+	}
+	bbox, _ := marksBBox(marks)
+	return strings.Join(texts, " "), bbox
+}
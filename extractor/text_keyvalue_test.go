@@ -0,0 +1,70 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package extractor
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+// TestKeyValues checks that PageText.KeyValues() finds inline and column label-value pairs.
+func TestKeyValues(t *testing.T) {
+	contents := `
+        BT
+        /UniDocHelveticaBold 14 Tf
+        1 0 0 1 10 750 Tm (Totals) Tj
+        /UniDocHelvetica 10 Tf
+        1 0 0 1 10 700 Tm (Name: John Smith) Tj
+        1 0 0 1 200 700 Tm (Date: 2020-01-02) Tj
+        1 0 0 1 10 680 Tm (Address:) Tj
+        1 0 0 1 150 680 Tm (12 Long St) Tj
+        1 0 0 1 10 640 Tm (No labels in this sentence.) Tj
+        /UniDocHelveticaBold 10 Tf
+        1 0 0 1 10 660 Tm (Invoice) Tj
+        /UniDocHelvetica 10 Tf
+        1 0 0 1 150 660 Tm (INV-42) Tj
+        ET`
+	pt := fragmentPageText(t, contents)
+	expected := []struct {
+		key, value string
+		valueLlx   float64
+	}{
+		{"Name", "John Smith", 42.23},
+		{"Date", "2020-01-02", 226.68},
+		{"Address", "12 Long St", 150},
+		{"Invoice", "INV-42", 150},
+	}
+	kvs := pt.KeyValues()
+	if len(kvs) != len(expected) {
+		t.Fatalf("%d pairs expected %d. pairs=%v", len(kvs), len(expected), kvs)
+	}
+	for i, kv := range kvs {
+		exp := expected[i]
+		if kv.Key != exp.key || kv.Value != exp.value ||
+			math.Abs(kv.ValueBBox.Llx-exp.valueLlx) > 0.01 {
+			t.Fatalf("pair %d: %s value at x=%.2f expected %q=%q at x=%.2f", i, kv,
+				kv.ValueBBox.Llx, exp.key, exp.value, exp.valueLlx)
+		}
+	}
+}
+
+// TestKeyValuesTruncated checks that KeyValues() works on pages whose text is truncated because
+// the extractor is unlicensed.
+func TestKeyValuesTruncated(t *testing.T) {
+	contents := `
+        BT
+        /UniDocHelvetica 10 Tf
+        1 0 0 1 10 700 Tm (Name: John Smith) Tj
+        1 0 0 1 10 680 Tm 12 TL
+        ` + strings.Repeat("(Some long body text that fills the page.) '\n", 10) + `
+        ET`
+	pt := unlicensedPageText(t, contents)
+	kvs := pt.KeyValues()
+	if len(kvs) != 1 || kvs[0].Key != "Name" || kvs[0].Value != "John Smith" {
+		t.Fatalf("pairs=%v expected Name=John Smith", kvs)
+	}
+}
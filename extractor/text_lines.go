@@ -0,0 +1,215 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package extractor
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+// TextLine is a line of the extracted text of a page.
+type TextLine struct {
+	// Text is the text of the line's marks. It is the substring Text()[Offset:Offset+len(Text)] of
+	// the page's extracted text unless that text has been truncated.
+	Text string
+	// Offset is the offset of the start of the line in the page's extracted text.
+	Offset int
+	// Marks are the TextMarks of the line.
+	Marks *TextMarkArray
+	// BBox is the bounding box of the line's text.
+	BBox model.PdfRectangle
+	// SoftWrap is true if the line break after the line appears to be where the text was wrapped
+	// to fit the width of a block of text, such as a paragraph, rather than an intentional break,
+	// such as the end of an address line or a line of poetry. Soft wrapped lines can be joined
+	// when reflowing text. It is false for the last line of each block of text.
+	SoftWrap bool
+	// Font and FontSize are the font and font size that cover the largest area of the line's
+	// non-space text. They can be used to find headings, which are often single lines in a larger
+	// or bolder font than body text. Font is nil if the line has no such text.
+	Font     *model.PdfFont
+	FontSize float64
+}
+
+// String returns a string describing `tl`.
+func (tl TextLine) String() string {
+	b := tl.BBox
+	var soft string
+	if tl.SoftWrap {
+		soft = " SOFT"
+	}
+	return fmt.Sprintf("{TextLine: %d (%5.1f, %5.1f) (%5.1f, %5.1f) %q%s}",
+		tl.Offset, b.Llx, b.Lly, b.Urx, b.Ury, tl.Text, soft)
+}
+
+// Lines returns the lines of the extracted text of `pt` in the order they appear in Text().
+func (pt PageText) Lines() []TextLine {
+	var lines []TextLine
+	for _, marks := range pt.viewLines() {
+		if len(marks) == 0 {
+			continue
+		}
+		bbox, _ := marksBBox(marks)
+		font, size := dominantFont(marks)
+		lines = append(lines, TextLine{
+			Text:     marksText(marks),
+			Offset:   marks[0].Offset,
+			Marks:    &TextMarkArray{marks: marks},
+			BBox:     bbox,
+			Font:     font,
+			FontSize: size,
+		})
+	}
+	setSoftWraps(lines)
+	return lines
+}
+
+// marksText returns the text of `marks`.
+// The text is built from the marks rather than sliced from the page text with their offsets
+// because the page text may be truncated.
+func marksText(marks []TextMark) string {
+	var b strings.Builder
+	for _, tm := range marks {
+		b.WriteString(tm.Text)
+	}
+	return b.String()
+}
+
+// SameLine returns true if marks `a` and `b`, which are marks of `pt` such as those returned by
+// Marks(), are on the same line of the extracted text. This is the extractor's own definition of a
+// line, so callers that group marks themselves get lines that are consistent with Text() and
+// Lines(). Marks that are not marks of `pt` are not on any line.
+func (pt PageText) SameLine(a, b TextMark) bool {
+	i := pt.lineIndex(a)
+	return i >= 0 && i == pt.lineIndex(b)
+}
+
+// lineIndex returns the index of the line of `pt` that `tm` is on, or -1 if `tm` is not a mark of
+// `pt`. The line separator after a line is counted as part of it.
+func (pt PageText) lineIndex(tm TextMark) int {
+	marks := pt.viewMarks
+	k := sort.Search(len(marks), func(k int) bool { return marks[k].Offset >= tm.Offset })
+	// Marks with empty text, such as empty line separators, have the same offset as the next mark.
+	for ; k < len(marks) && marks[k].Offset == tm.Offset; k++ {
+		if marks[k].Text == tm.Text {
+			return sort.SearchInts(pt.viewLineStarts, k+1) - 1
+		}
+	}
+	return -1
+}
+
+// dominantFont returns the font and font size that cover the largest area of the non-space marks
+// in `marks`. It returns a nil font if there are no such marks.
+func dominantFont(marks []TextMark) (*model.PdfFont, float64) {
+	type fontKey struct {
+		font *model.PdfFont
+		size float64
+	}
+	areas := map[fontKey]float64{}
+	var best fontKey
+	bestArea := -1.0
+	for _, tm := range marks {
+		if tm.Meta || tm.Font == nil || isTextSpace(tm.Text) {
+			continue
+		}
+		k := fontKey{tm.Font, tm.FontSize}
+		b := tm.BBox
+		areas[k] += math.Abs((b.Urx - b.Llx) * (b.Ury - b.Lly))
+		if areas[k] > bestArea {
+			best, bestArea = k, areas[k]
+		}
+	}
+	return best.font, best.size
+}
+
+const (
+	// wrapLineGap is the maximum gap between successive lines in a block of text as a fraction of
+	// the line height.
+	wrapLineGap = 1.0
+	// wrapIndent is the maximum difference between the left edges of successive lines in a block
+	// of text as a fraction of the line height. This allows for indented first lines.
+	wrapIndent = 3.0
+	// wrapRoom is the maximum space between the end of a soft wrapped line and the right edge of
+	// its block as a fraction of the block's width.
+	wrapRoom = 0.2
+)
+
+// setSoftWraps sets the SoftWrap field of `lines`.
+// `lines` are grouped into blocks of successive lines that are close together and roughly left
+// aligned. A line break in a block is soft if the line ends with a hyphen, or if the line ends
+// near the right edge of the block and the first word of the next line would not have fitted
+// between the end of the line and the right edge. Otherwise the line break is hard because the
+// line was ended before it needed to be.
+// NOTE: The widest line of a block always reaches its right edge so a hard break after it is only
+// detected if the next line starts with a short word. e.g. In an address block whose longest line
+// is not the last.
+func setSoftWraps(lines []TextLine) {
+	for _, block := range textBlocks(lines) {
+		left, right := math.Inf(1), math.Inf(-1)
+		for _, tl := range block {
+			left = math.Min(left, tl.BBox.Llx)
+			right = math.Max(right, tl.BBox.Urx)
+		}
+		for i := range block[:len(block)-1] {
+			tl := block[i]
+			if strings.HasSuffix(tl.Text, "-") {
+				block[i].SoftWrap = true
+				continue
+			}
+			room := right - tl.BBox.Urx
+			block[i].SoftWrap = room <= wrapRoom*(right-left) && firstWordWidth(block[i+1]) >= room
+		}
+	}
+}
+
+// textBlocks returns `lines` split into blocks of successive lines that are close together and
+// roughly left aligned. The blocks are slices of `lines`.
+func textBlocks(lines []TextLine) [][]TextLine {
+	var blocks [][]TextLine
+	for i0 := 0; i0 < len(lines); {
+		i1 := i0 + 1
+		for i1 < len(lines) && inSameBlock(lines[i1-1], lines[i1]) {
+			i1++
+		}
+		blocks = append(blocks, lines[i0:i1])
+		i0 = i1
+	}
+	return blocks
+}
+
+// inSameBlock returns true if `tl` and `next`, the line after it, appear to be in the same block
+// of text.
+func inSameBlock(tl, next TextLine) bool {
+	h := tl.BBox.Ury - tl.BBox.Lly
+	if h <= 0 {
+		return false
+	}
+	gap := tl.BBox.Lly - next.BBox.Ury
+	return gap >= -0.5*h && gap <= wrapLineGap*h &&
+		math.Abs(tl.BBox.Llx-next.BBox.Llx) <= wrapIndent*h
+}
+
+// firstWordWidth returns the width of the first word of `tl` plus the width of the space that
+// would precede it if it were moved to the end of the previous line.
+func firstWordWidth(tl TextLine) float64 {
+	marks := tl.Marks.Elements()
+	n := 0
+	for n < len(marks) && !marks[n].Meta && !isTextSpace(marks[n].Text) {
+		n++
+	}
+	bbox, ok := marksBBox(marks[:n])
+	if !ok {
+		return 0
+	}
+	// Use the line's average character width as the width of a space.
+	lineWidth := tl.BBox.Urx - tl.BBox.Llx
+	space := lineWidth / float64(utf8.RuneCountInString(tl.Text))
+	return bbox.Urx - bbox.Llx + space
+}
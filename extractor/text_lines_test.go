@@ -0,0 +1,161 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package extractor
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// TestLinesSoftWrap checks that PageText.Lines() distinguishes wrapped lines in a paragraph from
+// intentional line breaks in an address.
+func TestLinesSoftWrap(t *testing.T) {
+	contents := `
+        BT
+        /UniDocCourier 10 Tf
+        12 TL
+        10 700 Td
+        (The quick brown fox jumps over the lazy) Tj
+        (dog and then runs far away into the dark) '
+        (woods.) '
+        0 -60 Td
+        (John Smith) Tj
+        (12 Long St) '
+        (Springfield IL 62701) '
+        ET`
+	pt := fragmentPageText(t, contents)
+	expected := []struct {
+		text     string
+		softWrap bool
+	}{
+		{"The quick brown fox jumps over the lazy", true},
+		{"dog and then runs far away into the dark", true},
+		{"woods.", false},
+		{"John Smith", false},
+		{"12 Long St", false},
+		{"Springfield IL 62701", false},
+	}
+	lines := pt.Lines()
+	if len(lines) != len(expected) {
+		t.Fatalf("%d lines expected %d. lines=%v", len(lines), len(expected), lines)
+	}
+	text := pt.Text()
+	for i, tl := range lines {
+		exp := expected[i]
+		if tl.Text != exp.text || tl.SoftWrap != exp.softWrap {
+			t.Fatalf("line %d: %s expected %q SoftWrap=%t", i, tl, exp.text, exp.softWrap)
+		}
+		if text[tl.Offset:tl.Offset+len(tl.Text)] != tl.Text {
+			t.Fatalf("line %d: inconsistent offset %d", i, tl.Offset)
+		}
+	}
+}
+
+// TestLinesFont checks that each TextLine has the font and size of most of its text.
+func TestLinesFont(t *testing.T) {
+	contents := `
+        BT
+        /UniDocHelveticaBold 18 Tf
+        10 700 Td (Introduction) Tj
+        /UniDocHelveticaBold 10 Tf
+        0 -30 Td (Note: ) Tj
+        /UniDocHelvetica 10 Tf
+        (this line is mostly body text.) Tj
+        ET`
+	pt := fragmentPageText(t, contents)
+	expected := []struct {
+		font string
+		size float64
+	}{
+		{"Helvetica-Bold", 18},
+		{"Helvetica", 10},
+	}
+	lines := pt.Lines()
+	if len(lines) != len(expected) {
+		t.Fatalf("%d lines expected %d. lines=%v", len(lines), len(expected), lines)
+	}
+	for i, tl := range lines {
+		exp := expected[i]
+		if tl.Font == nil || tl.Font.BaseFont() != exp.font || tl.FontSize != exp.size {
+			t.Fatalf("line %d: %s font=%v size=%g expected %s %g", i, tl, tl.Font, tl.FontSize,
+				exp.font, exp.size)
+		}
+	}
+}
+
+// TestSameLine checks that PageText.SameLine() agrees with the lines of PageText.Lines(),
+// including for marks that are slightly above the baseline and when the line separator is empty.
+func TestSameLine(t *testing.T) {
+	contents := `
+        BT
+        /UniDocCourier 10 Tf
+        10 700 Td (Hello ) Tj 1 Ts (there) Tj 0 Ts
+        0 -20 Td (World) Tj
+        ET`
+	for _, sep := range []string{"\n", ""} {
+		e := NewFromContents(contents, fragmentResources(), fragmentMediaBox)
+		e.options.LineSeparator = sep
+		pt, _, _, err := e.ExtractPageText()
+		if err != nil {
+			t.Fatalf("ExtractPageText failed. err=%v", err)
+		}
+		lines := pt.Lines()
+		if len(lines) != 2 {
+			t.Fatalf("sep=%q: %d lines expected 2. lines=%v", sep, len(lines), lines)
+		}
+		for i, li := range lines {
+			for j, lj := range lines {
+				for _, a := range li.Marks.Elements() {
+					for _, b := range lj.Marks.Elements() {
+						if same := pt.SameLine(a, b); same != (i == j) {
+							t.Fatalf("sep=%q: SameLine(%s, %s)=%t expected %t", sep, a, b, same,
+								i == j)
+						}
+					}
+				}
+			}
+		}
+		if pt.SameLine(TextMark{Text: "x", Offset: 1}, lines[0].Marks.Elements()[1]) {
+			t.Fatalf("sep=%q: a mark that is not on the page is on a line", sep)
+		}
+	}
+}
+
+// TestLinesTruncated checks that Lines() works on pages whose text is truncated because the
+// extractor is unlicensed.
+func TestLinesTruncated(t *testing.T) {
+	var expected []string
+	var b strings.Builder
+	b.WriteString("BT /UniDocCourier 10 Tf 12 TL 10 700 Td\n")
+	for i := 0; i < 10; i++ {
+		text := fmt.Sprintf("Line %d of the long text", i)
+		expected = append(expected, text)
+		fmt.Fprintf(&b, "(%s) '\n", text)
+	}
+	b.WriteString("ET")
+	pt := unlicensedPageText(t, b.String())
+	if len(pt.Marks().Elements()) >= len(strings.Join(expected, "")) {
+		t.Fatalf("text was not truncated. text=%q", pt.Text())
+	}
+	lines := pt.Lines()
+	if len(lines) == 0 {
+		t.Fatalf("no lines")
+	}
+	for i, tl := range lines {
+		if !strings.HasPrefix(expected[i], tl.Text) || tl.Text == "" {
+			t.Fatalf("line %d: %s expected a prefix of %q", i, tl, expected[i])
+		}
+	}
+}
+
+// unlicensedPageText returns the PageText of `contents` extracted as it is by an unlicensed
+// extractor, which truncates the text of pages.
+func unlicensedPageText(t *testing.T, contents string) *PageText {
+	isTesting = false
+	defer func() { isTesting = true }()
+	return fragmentPageText(t, contents)
+}
@@ -0,0 +1,264 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package extractor
+
+import (
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+// Markdown returns the text on the page formatted as Markdown.
+// Lines with fonts that are rendered larger than the page's body text are written as headings,
+// with the largest rendered font size on the page being heading level 1. Lines that start with bullets or numbers
+// are written as list items, and text in bold or italic fonts is emphasized with ** or *. The
+// remaining lines are grouped into paragraphs by their vertical spacing.
+// NOTE: Tables are not detected so table rows are written as plain lines.
+func (pt PageText) Markdown() string {
+	var lines []mdLine
+	for _, marks := range pt.viewLines() {
+		if l, ok := newMdLine(marks); ok {
+			lines = append(lines, l)
+		}
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	levels := headingLevels(lines)
+
+	var blocks []string
+	var para []string
+	flush := func() {
+		if len(para) > 0 {
+			blocks = append(blocks, strings.Join(para, "\n"))
+			para = nil
+		}
+	}
+	for i, l := range lines {
+		if level, ok := levels[roundFontSize(l.size)]; ok {
+			flush()
+			blocks = append(blocks, strings.Repeat("#", level)+" "+mdEscape(l.plainText()))
+			continue
+		}
+		if prefix, marks, ok := l.listItem(); ok {
+			flush()
+			blocks = append(blocks, prefix+mdEmphasize(marks))
+			continue
+		}
+		if i > 0 && !lines[i-1].sameParagraph(l) {
+			flush()
+		}
+		para = append(para, mdEmphasize(l.marks))
+	}
+	flush()
+	return strings.Join(blocks, "\n\n")
+}
+
+const (
+	// mdHeadingRatio is the minimum ratio of a line's font size to the body text font size for the
+	// line to be treated as a heading.
+	mdHeadingRatio = 1.15
+	// mdParaGap is the maximum vertical distance between baselines of successive lines in the same
+	// paragraph as a fraction of the lines' font size.
+	mdParaGap = 1.5
+)
+
+var (
+	// mdBulletRe matches the bullets at the start of unordered list items.
+	mdBulletRe = regexp.MustCompile(`^[•◦▪‣●○■□*\-–]\s+`)
+	// mdNumberRe matches the numbers at the start of ordered list items.
+	mdNumberRe = regexp.MustCompile(`^(\d+)[.)]\s+`)
+)
+
+// mdLine is a line of text that is to be written as Markdown.
+type mdLine struct {
+	marks []TextMark // The marks in the line.
+	text  string     // The line's text.
+	size  float64    // The font size covering most of the line.
+	y     float64    // The bottom of the line.
+}
+
+// newMdLine returns the mdLine for the line `marks`. It returns false if the line is empty.
+func newMdLine(marks []TextMark) (mdLine, bool) {
+	var parts []string
+	y := math.MaxFloat64
+	for _, tm := range marks {
+		parts = append(parts, tm.Text)
+		if !tm.Meta && !isTextSpace(tm.Text) {
+			y = math.Min(y, tm.BBox.Lly)
+		}
+	}
+	text := strings.Join(parts, "")
+	if strings.TrimSpace(text) == "" {
+		return mdLine{}, false
+	}
+	return mdLine{marks: marks, text: text, size: dominantFontSize(marks), y: y}, true
+}
+
+// plainText returns the text of `l` with leading and trailing spaces removed.
+func (l mdLine) plainText() string {
+	return strings.TrimSpace(l.text)
+}
+
+// listItem returns the Markdown list item prefix for `l` and the marks in `l` after the bullet
+// or number, if `l` is a list item.
+func (l mdLine) listItem() (string, []TextMark, bool) {
+	var prefix string
+	loc := mdBulletRe.FindStringIndex(l.text)
+	if loc != nil {
+		prefix = "- "
+	} else if m := mdNumberRe.FindStringSubmatchIndex(l.text); m != nil {
+		loc = m[:2]
+		prefix = l.text[m[2]:m[3]] + ". "
+	} else {
+		return "", nil, false
+	}
+	i := 0
+	for i < len(l.marks) && l.marks[i].Offset-l.marks[0].Offset < loc[1] {
+		i++
+	}
+	return prefix, l.marks[i:], true
+}
+
+// sameParagraph returns true if `l` and the following line `next` are in the same paragraph.
+func (l mdLine) sameParagraph(next mdLine) bool {
+	if next.y > l.y {
+		return false
+	}
+	return l.y-next.y <= mdParaGap*math.Max(l.size, next.size)
+}
+
+// headingLevels returns a map of {font size: heading level} for the font sizes in `lines` that
+// are large enough to be headings.
+func headingLevels(lines []mdLine) map[float64]int {
+	var marks []TextMark
+	for _, l := range lines {
+		marks = append(marks, l.marks...)
+	}
+	body := dominantFontSize(marks)
+	seen := map[float64]bool{}
+	var sizes []float64
+	for _, l := range lines {
+		size := roundFontSize(l.size)
+		if size >= body*mdHeadingRatio && !seen[size] {
+			seen[size] = true
+			sizes = append(sizes, size)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.Float64Slice(sizes)))
+	levels := map[float64]int{}
+	for i, size := range sizes {
+		if i == 6 {
+			break
+		}
+		levels[size] = i + 1
+	}
+	return levels
+}
+
+// dominantFontSize returns the rendered font size of the most non-space characters in `marks`.
+// This is the size the text appears at on the page, so text drawn with a small Tf font size that
+// is scaled up by the text or graphics matrix is as large as text drawn with a large Tf size.
+func dominantFontSize(marks []TextMark) float64 {
+	counts := map[float64]int{}
+	for _, tm := range marks {
+		if tm.Meta || isTextSpace(tm.Text) {
+			continue
+		}
+		counts[roundFontSize(tm.EffectiveFontSize)] += len([]rune(tm.Text))
+	}
+	best, bestCount := 0.0, 0
+	for size, n := range counts {
+		if n > bestCount || (n == bestCount && size < best) {
+			best, bestCount = size, n
+		}
+	}
+	return best
+}
+
+// roundFontSize returns `size` rounded to the nearest half point so that font sizes that differ
+// by rounding errors compare equal.
+func roundFontSize(size float64) float64 {
+	return math.Round(size*2) / 2
+}
+
+// mdEmphasize returns the text of `marks` as Markdown with the text in bold fonts surrounded by
+// ** and the text in italic fonts surrounded by *.
+func mdEmphasize(marks []TextMark) string {
+	type run struct {
+		bold, italic bool
+		text         string
+	}
+	var runs []run
+	for _, tm := range marks {
+		text := mdEscape(tm.Text)
+		if len(runs) > 0 && (tm.Meta || isTextSpace(tm.Text)) {
+			runs[len(runs)-1].text += text
+			continue
+		}
+		bold, italic := fontStyle(tm.Font)
+		if n := len(runs); n > 0 && runs[n-1].bold == bold && runs[n-1].italic == italic {
+			runs[n-1].text += text
+			continue
+		}
+		runs = append(runs, run{bold: bold, italic: italic, text: text})
+	}
+
+	var b strings.Builder
+	for _, r := range runs {
+		marker := ""
+		if r.bold {
+			marker += "**"
+		}
+		if r.italic {
+			marker += "*"
+		}
+		text := strings.TrimSpace(r.text)
+		if marker == "" || text == "" {
+			b.WriteString(r.text)
+			continue
+		}
+		i := strings.Index(r.text, text)
+		b.WriteString(r.text[:i] + marker + text + marker + r.text[i+len(text):])
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// mdEscaper escapes the characters that have special meanings in Markdown inline text.
+var mdEscaper = strings.NewReplacer(`\`, `\\`, "*", `\*`, "_", `\_`, "`", "\\`")
+
+// mdEscape returns `text` with characters that have special meanings in Markdown escaped.
+func mdEscape(text string) string {
+	return mdEscaper.Replace(text)
+}
+
+// fontStyle returns true for `bold` (`italic`) if `font` is a bold (italic) font.
+func fontStyle(font *model.PdfFont) (bold, italic bool) {
+	if font == nil {
+		return false, false
+	}
+	name := strings.ToLower(font.BaseFont())
+	bold = strings.Contains(name, "bold") || strings.Contains(name, "black") ||
+		strings.Contains(name, "heavy")
+	italic = strings.Contains(name, "italic") || strings.Contains(name, "oblique")
+	if desc, _ := font.GetFontDescriptor(); desc != nil {
+		if flags, ok := core.GetIntVal(desc.Flags); ok {
+			bold = bold || flags&fontFlagForceBold != 0
+			italic = italic || flags&fontFlagItalic != 0
+		}
+	}
+	return bold, italic
+}
+
+// Font descriptor flags. See Table 123 in the PDF 32000 spec.
+const (
+	fontFlagItalic    = 0x00040
+	fontFlagForceBold = 0x40000
+)
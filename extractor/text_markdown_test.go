@@ -0,0 +1,56 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package extractor
+
+import (
+	"testing"
+)
+
+// TestMarkdown checks that PageText.Markdown() infers headings, lists, paragraphs and emphasis.
+func TestMarkdown(t *testing.T) {
+	contents := `
+        BT
+        /UniDocHelveticaBold 24 Tf
+        72 700 Td (Title) Tj
+        /UniDocHelveticaBold 16 Tf
+        0 -40 Td (Section) Tj
+        /UniDocHelvetica 10 Tf
+        0 -30 Td (This is the first) Tj
+        0 -12 Td (paragraph of text.) Tj
+        0 -30 Td (A) Tj
+        /UniDocHelveticaBold 10 Tf
+        (bold_word) Tj
+        /UniDocHelvetica 10 Tf
+        0 -30 Td (- Apples) Tj
+        0 -12 Td (2. Pears) Tj
+        ET
+        `
+	pt := fragmentPageText(t, contents)
+	md := pt.Markdown()
+	expected := "# Title\n\n## Section\n\nThis is the first\nparagraph of text.\n\n" +
+		"A**bold\\_word**\n\n- Apples\n\n2. Pears"
+	if md != expected {
+		t.Fatalf("Markdown mismatch.\ngot:\n%s\nexpected:\n%s", md, expected)
+	}
+}
+
+// TestMarkdownScaledText checks that PageText.Markdown() finds headings by the size text is rendered
+// at rather than by the Tf font size, for text drawn with a unit font size scaled by Tm.
+func TestMarkdownScaledText(t *testing.T) {
+	contents := `
+        BT
+        /UniDocHelveticaBold 1 Tf
+        24 0 0 24 72 700 Tm (Title) Tj
+        /UniDocHelvetica 1 Tf
+        10 0 0 10 72 660 Tm (Body text.) Tj
+        ET
+        `
+	pt := fragmentPageText(t, contents)
+	expected := "# Title\n\nBody text."
+	if md := pt.Markdown(); md != expected {
+		t.Fatalf("Markdown mismatch.\ngot:\n%s\nexpected:\n%s", md, expected)
+	}
+}
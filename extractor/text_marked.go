@@ -0,0 +1,147 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package extractor
+
+import (
+	"github.com/unidoc/unipdf/v3/common"
+	"github.com/unidoc/unipdf/v3/contentstream"
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+// markedContent is a marked content sequence started by a BMC or BDC operator.
+// See section 14.6 "Marked Content" in the PDF 32000 spec.
+type markedContent struct {
+	tag  string                    // The tag that identifies the role of the sequence.
+	mcid int                       // The marked content identifier. -1 if there is none.
+	prop *core.PdfObjectDictionary // The property list of BDC sequences. nil for BMC.
+	lang string                    // The /Lang language identifier. "" if there is none.
+	// actualText is the replacement text of sequences with an /ActualText entry. nil if there is
+	// none.
+	actualText *actualTextSpan
+}
+
+// actualTextSpan is a marked content sequence with an /ActualText entry. The text of the glyphs
+// drawn in the sequence is replaced by `text`. See section 14.9.4 "Replacement Text" in the
+// PDF 32000 spec.
+type actualTextSpan struct {
+	text string
+}
+
+// newMarkedContent returns the marked content sequence started by BMC or BDC operator `op`.
+// Named property lists of BDC operators are looked up in the /Properties of `resources`.
+func newMarkedContent(op *contentstream.ContentStreamOperation,
+	resources *model.PdfPageResources) markedContent {
+	mc := markedContent{mcid: -1}
+	if len(op.Params) == 0 {
+		common.Log.Debug("ERROR: %s has no tag", op.Operand)
+		return mc
+	}
+	mc.tag, _ = core.GetNameVal(op.Params[0])
+	if op.Operand != "BDC" || len(op.Params) < 2 {
+		return mc
+	}
+
+	prop, ok := core.GetDict(op.Params[1])
+	if !ok {
+		name, isName := core.GetNameVal(op.Params[1])
+		if isName && resources != nil {
+			if props, isDict := core.GetDict(resources.Properties); isDict {
+				prop, ok = core.GetDict(props.Get(core.PdfObjectName(name)))
+			}
+		}
+	}
+	if !ok {
+		common.Log.Debug("BDC property list not found. op=%s", op)
+		return mc
+	}
+	mc.prop = prop
+	if mcid, ok := core.GetIntVal(prop.Get("MCID")); ok {
+		mc.mcid = mcid
+	}
+	if str, ok := core.GetString(prop.Get("Lang")); ok {
+		mc.lang = str.Decoded()
+	}
+	if str, ok := core.GetString(prop.Get("ActualText")); ok {
+		mc.actualText = &actualTextSpan{text: str.Decoded()}
+	}
+	return mc
+}
+
+// currentMCID returns the MCID of the innermost marked content sequence in `mcStack` that has one,
+// or -1 if none do.
+func currentMCID(mcStack []markedContent) int {
+	for i := len(mcStack) - 1; i >= 0; i-- {
+		if mcStack[i].mcid >= 0 {
+			return mcStack[i].mcid
+		}
+	}
+	return -1
+}
+
+// currentLang returns the language of the innermost marked content sequence in `mcStack` that
+// specifies one, or "" if none do.
+func currentLang(mcStack []markedContent) string {
+	for i := len(mcStack) - 1; i >= 0; i-- {
+		if mcStack[i].lang != "" {
+			return mcStack[i].lang
+		}
+	}
+	return ""
+}
+
+// currentActualText returns the outermost marked content sequence in `mcStack` with replacement
+// text, or nil if none have any. The replacement text of a sequence replaces all the text drawn
+// in it, including that of nested sequences.
+func currentActualText(mcStack []markedContent) *actualTextSpan {
+	for _, mc := range mcStack {
+		if mc.actualText != nil {
+			return mc.actualText
+		}
+	}
+	return nil
+}
+
+// replaceActualText returns `marks` with the marks drawn in each marked content sequence with
+// replacement text replaced by a single mark containing the replacement text. The replacement
+// text is an atomic unit that isn't split into words or merged by glyph position, since the
+// glyphs may be decorative or drawn in a different order to the text they represent. The
+// replacement mark covers the bounding box of the glyphs and takes its other properties from the
+// first glyph. Glyphs with an empty replacement text are dropped.
+func replaceActualText(marks []textMark) []textMark {
+	replaced := map[*actualTextSpan]int{} // {span: index of replacement mark in `out`}
+	out := make([]textMark, 0, len(marks))
+	for _, tm := range marks {
+		span := tm.actualText
+		if span == nil {
+			out = append(out, tm)
+			continue
+		}
+		i, ok := replaced[span]
+		if !ok {
+			replaced[span] = len(out)
+			tm.text = span.text
+			out = append(out, tm)
+			continue
+		}
+		r := &out[i]
+		r.original += tm.original
+		r.bbox = rectUnion(r.bbox, tm.bbox)
+		if tm.orientedEnd.X > r.orientedEnd.X {
+			r.orientedEnd = tm.orientedEnd
+			r.end = tm.end
+		}
+	}
+	n := 0
+	for _, tm := range out {
+		if tm.actualText != nil && tm.text == "" {
+			continue
+		}
+		out[n] = tm
+		n++
+	}
+	return out[:n]
+}
@@ -0,0 +1,218 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package extractor
+
+import (
+	"math"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+// MathRegions returns the bounding boxes of the regions of `pt` that are likely to contain
+// mathematical notation. Mathematical notation extracts poorly as linear text so these regions
+// may be worth passing to a specialized tool.
+// This is a heuristic. A word is mathematical if it contains a math symbol, a Greek letter or a
+// glyph from a math font, or if it has a superscript or subscript. Runs of such words in a line,
+// together with the single letter variables between and around them, are regions. Regions that
+// overlap horizontally on successive lines, such as the numerator and denominator of a fraction,
+// are merged.
+func (pt PageText) MathRegions() []model.PdfRectangle {
+	lines := pt.Lines()
+	words := make([][]mathWord, len(lines))
+	for i, tl := range lines {
+		for _, marks := range lineWords(tl.Marks.Elements()) {
+			words[i] = append(words[i], newMathWord(marks))
+		}
+	}
+	markScripts(words)
+
+	var regions []model.PdfRectangle
+	for _, line := range words {
+		regions = append(regions, lineMathRegions(line)...)
+	}
+	return mergeMathRegions(regions)
+}
+
+const (
+	// minMathMarks is the minimum number of mathematical marks in a region of one word.
+	minMathMarks = 2
+	// scriptSizeRatio is the maximum ratio of the font size of a superscript or subscript to the
+	// font size of the word it is attached to.
+	scriptSizeRatio = 0.9
+	// scriptShift is the minimum shift of the baseline of a superscript or subscript from the
+	// baseline of the word it is attached to as a fraction of the word's font size.
+	scriptShift = 0.15
+	// scriptGap is the maximum gap between a word and its superscript or subscript as a fraction
+	// of the word's font size.
+	scriptGap = 0.3
+	// scriptLines is the number of lines before and after a word that are searched for its
+	// superscripts and subscripts. Scripts are often put on lines of their own because their
+	// baselines are shifted.
+	scriptLines = 2
+)
+
+// mathWord is a word that may be part of a mathematical formula.
+type mathWord struct {
+	bbox     model.PdfRectangle // Bounding box of the word and its superscripts and subscripts.
+	size     float64            // Dominant font size of the word.
+	baseline float64            // Baseline of the text in the dominant font size.
+	numMath  int                // Number of mathematical marks in the word.
+	variable bool               // The word is a single letter.
+}
+
+// newMathWord returns the mathWord for the marks of a word `marks`.
+func newMathWord(marks []TextMark) mathWord {
+	bbox, _ := marksBBox(marks)
+	_, size := dominantFont(marks)
+	w := mathWord{bbox: bbox, size: size, baseline: bbox.Lly, variable: isVariable(marks)}
+	for _, tm := range marks {
+		if tm.FontSize == size {
+			w.baseline = tm.BBox.Lly
+			break
+		}
+	}
+	for _, tm := range marks {
+		if isMathText(tm.Text) || isMathFont(tm.Font) {
+			w.numMath++
+		}
+	}
+	return w
+}
+
+// markScripts finds the words in `words`, the words of each line, that are superscripts or
+// subscripts of words on nearby lines. The scripts and the words they are attached to are counted
+// as mathematical and the scripts are added to the bounding boxes of the words they are attached
+// to.
+func markScripts(words [][]mathWord) {
+	for i := range words {
+		for k := range words[i] {
+			s := &words[i][k]
+			for j := i - scriptLines; j <= i+scriptLines; j++ {
+				if j < 0 || j == i || j >= len(words) {
+					continue
+				}
+				for l := range words[j] {
+					b := &words[j][l]
+					if isScript(*s, *b) {
+						s.numMath++
+						b.numMath++
+						b.bbox = rectUnion(b.bbox, s.bbox)
+					}
+				}
+			}
+		}
+	}
+}
+
+// isScript returns true if word `s` appears to be a superscript or subscript of word `b`.
+func isScript(s, b mathWord) bool {
+	if s.size <= 0 || s.size >= scriptSizeRatio*b.size {
+		return false
+	}
+	gap := s.bbox.Llx - b.bbox.Urx
+	return gap >= -scriptGap*b.size && gap <= scriptGap*b.size &&
+		math.Abs(s.baseline-b.baseline) > scriptShift*b.size &&
+		s.bbox.Lly < b.bbox.Ury && s.bbox.Ury > b.bbox.Lly
+}
+
+// lineMathRegions returns the regions of the words of a line, `words`, that are likely to contain
+// mathematical notation.
+func lineMathRegions(words []mathWord) []model.PdfRectangle {
+	var regions []model.PdfRectangle
+	var bbox model.PdfRectangle
+	numWords, numMath := 0, 0
+	addRun := func() {
+		if numMath >= minMathMarks || (numMath > 0 && numWords > 1) {
+			regions = append(regions, bbox)
+		}
+		numWords, numMath = 0, 0
+	}
+	for _, w := range words {
+		if w.numMath == 0 && !w.variable {
+			addRun()
+			continue
+		}
+		if numWords == 0 {
+			bbox = w.bbox
+		} else {
+			bbox = rectUnion(bbox, w.bbox)
+		}
+		numWords++
+		numMath += w.numMath
+	}
+	addRun()
+	return regions
+}
+
+// isMathText returns true if `text` contains math symbols, Greek letters or mathematical
+// alphanumeric symbols.
+func isMathText(text string) bool {
+	for _, r := range text {
+		if unicode.Is(unicode.Sm, r) || unicode.Is(unicode.Greek, r) ||
+			(r >= 0x1d400 && r <= 0x1d7ff) {
+			return true
+		}
+	}
+	return false
+}
+
+// mathFontNames are substrings of the names of fonts that are used for mathematical notation.
+var mathFontNames = []string{"SYMBOL", "MATH", "CMSY", "CMMI", "CMEX", "MSAM", "MSBM", "STIX"}
+
+// isMathFont returns true if `font` appears to be a font for mathematical notation.
+func isMathFont(font *model.PdfFont) bool {
+	if font == nil {
+		return false
+	}
+	name := strings.ToUpper(font.BaseFont())
+	for _, s := range mathFontNames {
+		if strings.Contains(name, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// isVariable returns true if `word` is a single letter, which may be a variable in a formula.
+func isVariable(word []TextMark) bool {
+	text := marksText(word)
+	r, _ := utf8.DecodeRuneInString(text)
+	return utf8.RuneCountInString(text) == 1 && unicode.IsLetter(r)
+}
+
+// mergeMathRegions returns `regions` with the regions that overlap or are on successive lines and
+// overlap horizontally merged.
+func mergeMathRegions(regions []model.PdfRectangle) []model.PdfRectangle {
+	for merged := true; merged; {
+		merged = false
+		for i := 0; i < len(regions) && !merged; i++ {
+			for j := i + 1; j < len(regions); j++ {
+				if !mathRegionsTouch(regions[i], regions[j]) {
+					continue
+				}
+				regions[i] = rectUnion(regions[i], regions[j])
+				regions = append(regions[:j], regions[j+1:]...)
+				merged = true
+				break
+			}
+		}
+	}
+	return regions
+}
+
+// mathRegionsTouch returns true if `a` and `b` overlap horizontally and are separated vertically
+// by less than the height of the shorter of them.
+func mathRegionsTouch(a, b model.PdfRectangle) bool {
+	if a.Urx < b.Llx || b.Urx < a.Llx {
+		return false
+	}
+	h := math.Min(a.Ury-a.Lly, b.Ury-b.Lly)
+	gap := math.Max(a.Lly, b.Lly) - math.Min(a.Ury, b.Ury)
+	return gap < h
+}
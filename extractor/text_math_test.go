@@ -0,0 +1,65 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package extractor
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+// TestMathRegions checks that PageText.MathRegions() finds an inline formula with a superscript
+// and a fraction, but not plain prose.
+func TestMathRegions(t *testing.T) {
+	contents := `
+        BT
+        /UniDocHelvetica 10 Tf
+        1 0 0 1 10 700 Tm (Plain prose without any formulas.) Tj
+        1 0 0 1 10 670 Tm (Energy is) Tj
+        1 0 0 1 80 670 Tm (E = mc) Tj
+        /UniDocHelvetica 6 Tf 4 Ts (2) Tj
+        /UniDocHelvetica 10 Tf 0 Ts ( in a vacuum.) Tj
+        1 0 0 1 100 640 Tm (a + b) Tj
+        1 0 0 1 100 626 Tm (c + d) Tj
+        ET`
+	pt := fragmentPageText(t, contents)
+	regions := pt.MathRegions()
+	expected := []model.PdfRectangle{
+		{Llx: 80, Lly: 670, Urx: 114.74, Ury: 680},
+		{Llx: 100, Lly: 626, Urx: 122.52, Ury: 650},
+	}
+	if len(regions) != len(expected) {
+		t.Fatalf("%d regions expected %d. regions=%v text=%q", len(regions), len(expected),
+			regions, pt.Text())
+	}
+	for i, r := range regions {
+		exp := expected[i]
+		if math.Abs(r.Llx-exp.Llx) > 0.01 || math.Abs(r.Lly-exp.Lly) > 0.01 ||
+			math.Abs(r.Urx-exp.Urx) > 0.01 || math.Abs(r.Ury-exp.Ury) > 0.01 {
+			t.Fatalf("region %d: %v expected %v", i, r, exp)
+		}
+	}
+}
+
+// TestMathRegionsTruncated checks that MathRegions() works on pages whose text is truncated
+// because the extractor is unlicensed.
+func TestMathRegionsTruncated(t *testing.T) {
+	contents := `
+        BT
+        /UniDocHelvetica 10 Tf
+        1 0 0 1 100 640 Tm (a + b) Tj
+        1 0 0 1 100 626 Tm (c + d) Tj
+        1 0 0 1 10 600 Tm 12 TL
+        ` + strings.Repeat("(Some long body text that fills the page.) '\n", 10) + `
+        ET`
+	pt := unlicensedPageText(t, contents)
+	regions := pt.MathRegions()
+	if len(regions) != 1 || math.Abs(regions[0].Llx-100) > 0.01 {
+		t.Fatalf("regions=%v expected 1 region at x=100", regions)
+	}
+}
@@ -0,0 +1,173 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package extractor
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+// NumberToken is a numeric or currency token found in the extracted text of a page.
+type NumberToken struct {
+	// Value is the parsed value of the token. Tokens like "-12.50" and "(12.50)" give -12.5. See
+	// NumberFormat.AccountingNegatives for when parentheses make a number negative.
+	Value float64
+	// Text is the token as it appears in the extracted text.
+	Text string
+	// BBox is the bounding box of the token on the page.
+	BBox model.PdfRectangle
+	// Offset is the offset of the start of Text in PageText.Text().
+	Offset int
+}
+
+// String returns a string describing `tok`.
+func (tok NumberToken) String() string {
+	b := tok.BBox
+	return fmt.Sprintf("{NumberToken: %d %q=%g (%5.1f, %5.1f) (%5.1f, %5.1f)}",
+		tok.Offset, tok.Text, tok.Value, b.Llx, b.Lly, b.Urx, b.Ury)
+}
+
+// NumberFormat describes how numbers are written in the text being searched.
+type NumberFormat struct {
+	// DecimalSeparator separates the integer and fractional parts of a number.
+	DecimalSeparator rune
+	// GroupSeparator separates groups of 3 digits in the integer part of a number.
+	GroupSeparator rune
+	// Pattern overrides the regular expression built from the separators if it is not nil.
+	// Matches are parsed with the separators above after currency symbols, signs and parentheses
+	// are removed.
+	Pattern *regexp.Regexp
+	// AccountingNegatives makes all numbers in parentheses negative, e.g. "(12)" gives -12. By
+	// default only numbers in parentheses with a currency symbol or a decimal separator, such as
+	// "($12)" or "(12.00)", are negative, so that footnote and list markers like "(1)" are not
+	// taken to be negative amounts.
+	AccountingNegatives bool
+}
+
+var (
+	// NumberFormatEnglish is the number format used in English speaking locales: 1,234.50
+	NumberFormatEnglish = NumberFormat{DecimalSeparator: '.', GroupSeparator: ','}
+	// NumberFormatEuropean is the number format used in many European locales: 1.234,50
+	NumberFormatEuropean = NumberFormat{DecimalSeparator: ',', GroupSeparator: '.'}
+)
+
+// NumberTokens returns the numeric and currency tokens in `pt` using NumberFormatEnglish.
+func (pt PageText) NumberTokens() []NumberToken {
+	return pt.NumberTokensFormat(NumberFormatEnglish)
+}
+
+// NumberTokensFormat returns the numeric and currency tokens in `pt` written in number format
+// `format`. The tokens are returned in the order they appear in pt.Text().
+func (pt PageText) NumberTokensFormat(format NumberFormat) []NumberToken {
+	re := format.Pattern
+	if re == nil {
+		re = format.regexp()
+	}
+	text := pt.viewText
+	var tokens []NumberToken
+	for _, loc := range re.FindAllStringIndex(text, -1) {
+		start, end := loc[0], loc[1]
+		if !isTokenBoundary(text, start, end) {
+			continue
+		}
+		s := text[start:end]
+		val, ok := format.parse(s)
+		if !ok {
+			continue
+		}
+		bbox, ok := marksBBox(rangeMarks(pt.viewMarks, start, end))
+		if !ok {
+			continue
+		}
+		tokens = append(tokens, NumberToken{Value: val, Text: s, BBox: bbox, Offset: start})
+	}
+	return tokens
+}
+
+// regexp returns a regular expression that matches numbers, optionally signed, parenthesized,
+// prefixed or suffixed by currency symbols and suffixed by %, written in `format`.
+func (format NumberFormat) regexp() *regexp.Regexp {
+	d := regexp.QuoteMeta(string(format.DecimalSeparator))
+	g := regexp.QuoteMeta(string(format.GroupSeparator))
+	num := `(?:\d{1,3}(?:` + g + `\d{3})+|\d+)(?:` + d + `\d+)?`
+	body := `[-+]?(?:\p{Sc} ?)?[-+]?` + num + `(?: ?\p{Sc})?`
+	// Parentheses are only part of a number if they enclose it.
+	pattern := `(?:\(` + body + `\)|` + body + `)%?`
+	return regexp.MustCompile(pattern)
+}
+
+// parse returns the numerical value of number token `s` written in `format`.
+// The number is negative if it is in accounting parentheses, as described in AccountingNegatives,
+// or if a '-' comes before its first digit. A '-' after the first digit ends the number, so a
+// token like "2020-07-01" that matches a custom Pattern is parsed as 2020.
+func (format NumberFormat) parse(s string) (float64, bool) {
+	negative := format.isAccountingNegative(s)
+	var b strings.Builder
+loop:
+	for _, r := range s {
+		switch {
+		case r == format.GroupSeparator:
+		case r == format.DecimalSeparator:
+			b.WriteRune('.')
+		case r == '-':
+			if b.Len() > 0 {
+				break loop
+			}
+			negative = true
+		case unicode.IsDigit(r):
+			b.WriteRune(r)
+		}
+	}
+	val, err := strconv.ParseFloat(b.String(), 64)
+	if err != nil {
+		return 0, false
+	}
+	if negative {
+		val = -val
+	}
+	return val, true
+}
+
+// isAccountingNegative returns true if number token `s` is in parentheses that make it negative.
+func (format NumberFormat) isAccountingNegative(s string) bool {
+	s = strings.TrimSuffix(s, "%")
+	if !strings.HasPrefix(s, "(") || !strings.HasSuffix(s, ")") {
+		return false
+	}
+	if format.AccountingNegatives || strings.ContainsRune(s, format.DecimalSeparator) {
+		return true
+	}
+	for _, r := range s {
+		if unicode.Is(unicode.Sc, r) {
+			return true
+		}
+	}
+	return false
+}
+
+// isTokenBoundary returns true if the text[start:end] is not immediately preceded or followed by
+// a letter or digit in `text`.
+func isTokenBoundary(text string, start, end int) bool {
+	if start > 0 {
+		r, _ := utf8.DecodeLastRuneInString(text[:start])
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return false
+		}
+	}
+	if end < len(text) {
+		r, _ := utf8.DecodeRuneInString(text[end:])
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,107 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package extractor
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// TestNumberTokens checks that PageText.NumberTokensFormat() finds the numbers in some text
+// fragments and parses them correctly.
+func TestNumberTokens(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		format   NumberFormat
+		expected []NumberToken
+	}{
+		{
+			name:   "invoice",
+			text:   "Total $1,234.50 Tax (12.00) Qty 3 ref A12",
+			format: NumberFormatEnglish,
+			expected: []NumberToken{
+				{Text: "$1,234.50", Value: 1234.5},
+				{Text: "(12.00)", Value: -12},
+				{Text: "3", Value: 3},
+			},
+		},
+		{
+			name:   "european",
+			text:   "Summe 1.234,50 $ Rabatt -5%",
+			format: NumberFormatEuropean,
+			expected: []NumberToken{
+				{Text: "1.234,50 $", Value: 1234.5},
+				{Text: "-5%", Value: -5},
+			},
+		},
+		{
+			name:   "signs",
+			text:   "Loss $-7.50 Gain +3 Debit (-2)",
+			format: NumberFormatEnglish,
+			expected: []NumberToken{
+				{Text: "$-7.50", Value: -7.5},
+				{Text: "+3", Value: 3},
+				{Text: "(-2)", Value: -2},
+			},
+		},
+		{
+			name:   "parentheses",
+			text:   "See note (1) and step 2) and (3 items cost ($4)",
+			format: NumberFormatEnglish,
+			expected: []NumberToken{
+				{Text: "(1)", Value: 1},
+				{Text: "2", Value: 2},
+				{Text: "3", Value: 3},
+				{Text: "($4)", Value: -4},
+			},
+		},
+		{
+			name: "accounting",
+			text: "Loss (5) and (6)%",
+			format: NumberFormat{DecimalSeparator: '.', GroupSeparator: ',',
+				AccountingNegatives: true},
+			expected: []NumberToken{
+				{Text: "(5)", Value: -5},
+				{Text: "(6)%", Value: -6},
+			},
+		},
+		{
+			name:   "pattern",
+			text:   "Invoice 2020-07-01 amount 17",
+			format: NumberFormat{DecimalSeparator: '.', Pattern: regexp.MustCompile(`\d+-\d+-\d+`)},
+			expected: []NumberToken{
+				{Text: "2020-07-01", Value: 2020},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Parentheses are escaped because they needn't be balanced in the text.
+			text := strings.NewReplacer("(", `\(`, ")", `\)`).Replace(test.text)
+			contents := "BT /UniDocCourier 10 Tf 10 100 Td (" + text + ") Tj ET"
+			pt := fragmentPageText(t, contents)
+			tokens := pt.NumberTokensFormat(test.format)
+			if len(tokens) != len(test.expected) {
+				t.Fatalf("%d tokens. expected %d. tokens=%v", len(tokens), len(test.expected), tokens)
+			}
+			for i, tok := range tokens {
+				exp := test.expected[i]
+				if tok.Text != exp.Text || tok.Value != exp.Value {
+					t.Fatalf("i=%d got %s. expected %s", i, tok, exp)
+				}
+				if pt.Text()[tok.Offset:tok.Offset+len(tok.Text)] != tok.Text {
+					t.Fatalf("i=%d bad offset. tok=%s", i, tok)
+				}
+				if tok.BBox.Llx < 10 || tok.BBox.Urx <= tok.BBox.Llx || tok.BBox.Ury <= tok.BBox.Lly {
+					t.Fatalf("i=%d bad bbox. tok=%s", i, tok)
+				}
+			}
+		})
+	}
+}
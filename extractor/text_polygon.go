@@ -0,0 +1,47 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package extractor
+
+import (
+	"github.com/unidoc/unipdf/v3/contentstream/draw"
+)
+
+// ApplyPolygon restricts `pt` to the text marks whose centers are inside the polygon with vertices
+// `points`, and lays out the remaining text again. The polygon is closed implicitly and may be
+// concave, e.g. an L-shaped sidebar. Polygons with fewer than 3 vertices contain no text.
+func (pt *PageText) ApplyPolygon(points []draw.Point) {
+	var marks []textMark
+	for _, tm := range pt.marks {
+		x := (tm.bbox.Llx + tm.bbox.Urx) / 2
+		y := (tm.bbox.Lly + tm.bbox.Ury) / 2
+		if insidePolygon(points, x, y) {
+			marks = append(marks, tm)
+		}
+	}
+	pt.marks = marks
+	pt.index = &markIndex{}
+	pt.computeViews()
+	procBuf(pt)
+}
+
+// insidePolygon returns true if (`x`, `y`) is inside the polygon with vertices `points`. It uses
+// the even-odd rule: a point is inside if a ray from it crosses the polygon's edges an odd number
+// of times.
+func insidePolygon(points []draw.Point, x, y float64) bool {
+	if len(points) < 3 {
+		return false
+	}
+	inside := false
+	j := len(points) - 1
+	for i, p := range points {
+		q := points[j]
+		if (p.Y > y) != (q.Y > y) && x < (q.X-p.X)*(y-p.Y)/(q.Y-p.Y)+p.X {
+			inside = !inside
+		}
+		j = i
+	}
+	return inside
+}
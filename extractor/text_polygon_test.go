@@ -0,0 +1,43 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package extractor
+
+import (
+	"testing"
+
+	"github.com/unidoc/unipdf/v3/contentstream/draw"
+)
+
+// TestApplyPolygon checks that PageText.ApplyPolygon keeps the text inside an L-shaped polygon.
+func TestApplyPolygon(t *testing.T) {
+	contents := `
+        BT
+        /UniDocCourier 10 Tf
+        1 0 0 1 10 700 Tm (Top left) Tj
+        1 0 0 1 300 700 Tm (Top right) Tj
+        1 0 0 1 10 600 Tm (Bottom left) Tj
+        1 0 0 1 300 600 Tm (Bottom right) Tj
+        ET`
+	pt := fragmentPageText(t, contents)
+	// An L around the left column and the bottom row.
+	pt.ApplyPolygon([]draw.Point{
+		{X: 0, Y: 750},
+		{X: 200, Y: 750},
+		{X: 200, Y: 650},
+		{X: 500, Y: 650},
+		{X: 500, Y: 550},
+		{X: 0, Y: 550},
+	})
+	expected := "Top left\nBottom left Bottom right"
+	if text := pt.Text(); text != expected {
+		t.Fatalf("text=%q expected %q", text, expected)
+	}
+
+	pt.ApplyPolygon(nil)
+	if text := pt.Text(); text != "" {
+		t.Fatalf("text=%q expected empty text", text)
+	}
+}
@@ -0,0 +1,274 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package extractor
+
+import (
+	"encoding/xml"
+	"fmt"
+	"image/color"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/unidoc/unipdf/v3/common"
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+// AnnotationText is the text of a markup annotation, such as a reviewer's comment in a FreeText
+// or Text (sticky note) annotation.
+type AnnotationText struct {
+	// Author is the /T entry, the name of the author of the annotation.
+	Author string
+	// BBox is the annotation's /Rect.
+	BBox model.PdfRectangle
+	// Contents is the plain text of the annotation from its /Contents entry.
+	Contents string
+	// RichText is the styled text of the annotation from its /RC rich text entry. It is nil if
+	// the annotation has no rich text. The rich text is often more complete than Contents.
+	RichText []RichTextRun
+}
+
+// RichTextRun is a run of annotation rich text with the same style. Style properties that the
+// rich text doesn't set are left at their zero values.
+type RichTextRun struct {
+	Text       string
+	Bold       bool
+	Italic     bool
+	Underline  bool
+	FontFamily string
+	// FontSize is the font size in points.
+	FontSize float64
+	// Color is the text color. It is nil if the rich text doesn't set it.
+	Color color.Color
+}
+
+// String returns a string describing `r`.
+func (r RichTextRun) String() string {
+	return fmt.Sprintf("{RichTextRun: %q bold=%t italic=%t underline=%t %q %g}", r.Text, r.Bold,
+		r.Italic, r.Underline, r.FontFamily, r.FontSize)
+}
+
+// ExtractAnnotationTexts returns the texts of the markup annotations on `page` that have text.
+// Annotations whose rich text can't be parsed are returned with their plain text only.
+func ExtractAnnotationTexts(page *model.PdfPage) ([]AnnotationText, error) {
+	annotations, err := page.GetAnnotations()
+	if err != nil {
+		return nil, err
+	}
+	var texts []AnnotationText
+	for _, annot := range annotations {
+		ctx, ok := annot.GetContext().(interface {
+			GetMarkup() *model.PdfAnnotationMarkup
+		})
+		if !ok || ctx.GetMarkup() == nil {
+			continue
+		}
+		markup := ctx.GetMarkup()
+		rc := markup.RC
+		if ft, ok := ctx.(*model.PdfAnnotationFreeText); ok && ft.RC != nil {
+			rc = ft.RC
+		}
+
+		var at AnnotationText
+		if str, ok := core.GetString(markup.T); ok {
+			at.Author = str.Decoded()
+		}
+		if str, ok := core.GetString(annot.Contents); ok {
+			at.Contents = str.Decoded()
+		}
+		if arr, ok := core.GetArray(annot.Rect); ok {
+			if rect, err := model.NewPdfRectangle(*arr); err == nil {
+				at.BBox = *rect
+			}
+		}
+		if xhtml, ok := richTextString(rc); ok {
+			at.RichText, err = ParseRichText(xhtml)
+			if err != nil {
+				common.Log.Debug("ERROR: Invalid annotation rich text. err=%v", err)
+			}
+		}
+		if at.Contents == "" && len(at.RichText) == 0 {
+			continue
+		}
+		texts = append(texts, at)
+	}
+	return texts, nil
+}
+
+// richTextString returns the rich text in `rc`, an /RC entry, which is a text string or a text
+// stream.
+func richTextString(rc core.PdfObject) (string, bool) {
+	if str, ok := core.GetString(rc); ok {
+		return str.Decoded(), true
+	}
+	if stream, ok := core.GetStream(rc); ok {
+		data, err := core.DecodeStream(stream)
+		if err != nil {
+			common.Log.Debug("ERROR: Invalid rich text stream. err=%v", err)
+			return "", false
+		}
+		return string(data), true
+	}
+	return "", false
+}
+
+// ParseRichText returns the styled runs of rich text string `xhtml`. Rich text strings are the
+// XHTML subset described in section 12.7.3.4 "Rich Text Strings" of the PDF 32000 spec. The <b>,
+// <i>, <u> and <span> elements and the font-weight, font-style, text-decoration, font-family,
+// font-size and color properties of style attributes are supported. Paragraphs and line breaks
+// are ended with "\n".
+func ParseRichText(xhtml string) ([]RichTextRun, error) {
+	decoder := xml.NewDecoder(strings.NewReader(xhtml))
+	decoder.Strict = false
+	decoder.AutoClose = xml.HTMLAutoClose
+	decoder.Entity = xml.HTMLEntity
+
+	var runs []RichTextRun
+	addText := func(style RichTextRun, text string) {
+		if text == "" {
+			return
+		}
+		if n := len(runs); n > 0 && sameRichTextStyle(runs[n-1], style) {
+			runs[n-1].Text += text
+			return
+		}
+		style.Text = text
+		runs = append(runs, style)
+	}
+	// endLine ends the current line if there is one.
+	endLine := func(style RichTextRun) {
+		if n := len(runs); n > 0 && !strings.HasSuffix(runs[n-1].Text, "\n") {
+			addText(style, "\n")
+		}
+	}
+
+	styles := []RichTextRun{{}}
+	for {
+		tok, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return runs, err
+		}
+		style := styles[len(styles)-1]
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch strings.ToLower(t.Name.Local) {
+			case "b", "strong":
+				style.Bold = true
+			case "i", "em":
+				style.Italic = true
+			case "u":
+				style.Underline = true
+			case "br":
+				addText(style, "\n")
+			}
+			for _, attr := range t.Attr {
+				if strings.ToLower(attr.Name.Local) == "style" {
+					style = applyRichTextStyle(style, attr.Value)
+				}
+			}
+			styles = append(styles, style)
+		case xml.EndElement:
+			switch strings.ToLower(t.Name.Local) {
+			case "p", "div":
+				endLine(style)
+			}
+			if len(styles) > 1 {
+				styles = styles[:len(styles)-1]
+			}
+		case xml.CharData:
+			// Line breaks between elements are formatting, not text.
+			text := string(t)
+			if strings.TrimSpace(text) == "" && strings.ContainsAny(text, "\r\n") {
+				continue
+			}
+			addText(style, text)
+		}
+	}
+	if n := len(runs); n > 0 {
+		runs[n-1].Text = strings.TrimSuffix(runs[n-1].Text, "\n")
+		if runs[n-1].Text == "" {
+			runs = runs[:n-1]
+		}
+	}
+	return runs, nil
+}
+
+// applyRichTextStyle returns `style` with the properties in CSS declarations `css` applied.
+func applyRichTextStyle(style RichTextRun, css string) RichTextRun {
+	for _, decl := range strings.Split(css, ";") {
+		parts := strings.SplitN(decl, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		name := strings.ToLower(strings.TrimSpace(parts[0]))
+		value := strings.TrimSpace(parts[1])
+		switch name {
+		case "font-weight":
+			weight, err := strconv.Atoi(value)
+			style.Bold = strings.EqualFold(value, "bold") || strings.EqualFold(value, "bolder") ||
+				(err == nil && weight >= 600)
+		case "font-style":
+			style.Italic = strings.EqualFold(value, "italic") || strings.EqualFold(value, "oblique")
+		case "text-decoration":
+			style.Underline = strings.Contains(strings.ToLower(value), "underline")
+		case "font-family":
+			style.FontFamily = strings.Trim(strings.Split(value, ",")[0], `"' `)
+		case "font-size":
+			value = strings.TrimSuffix(strings.ToLower(value), "pt")
+			if size, err := strconv.ParseFloat(value, 64); err == nil {
+				style.FontSize = size
+			}
+		case "color":
+			if c, ok := parseRichTextColor(value); ok {
+				style.Color = c
+			}
+		}
+	}
+	return style
+}
+
+// parseRichTextColor returns the color of CSS color `value`, which is in #rrggbb, #rgb or
+// rgb(r,g,b) form.
+func parseRichTextColor(value string) (color.Color, bool) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if strings.HasPrefix(value, "#") {
+		hex := value[1:]
+		if len(hex) == 3 {
+			hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+		}
+		v, err := strconv.ParseUint(hex, 16, 32)
+		if err != nil || len(hex) != 6 {
+			return nil, false
+		}
+		return color.RGBA{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v), A: 0xff}, true
+	}
+	if strings.HasPrefix(value, "rgb(") && strings.HasSuffix(value, ")") {
+		parts := strings.Split(value[4:len(value)-1], ",")
+		if len(parts) != 3 {
+			return nil, false
+		}
+		var rgb [3]uint8
+		for i, p := range parts {
+			v, err := strconv.Atoi(strings.TrimSpace(p))
+			if err != nil || v < 0 || v > 255 {
+				return nil, false
+			}
+			rgb[i] = uint8(v)
+		}
+		return color.RGBA{R: rgb[0], G: rgb[1], B: rgb[2], A: 0xff}, true
+	}
+	return nil, false
+}
+
+// sameRichTextStyle returns true if `a` and `b` have the same style.
+func sameRichTextStyle(a, b RichTextRun) bool {
+	a.Text, b.Text = "", ""
+	return a == b
+}
@@ -0,0 +1,101 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package extractor
+
+import (
+	"image/color"
+	"testing"
+
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+// richTextComment is a rich text string in the form that Acrobat writes.
+const richTextComment = `<?xml version="1.0"?>
+<body xmlns="http://www.w3.org/1999/xhtml" xmlns:xfa="http://www.xfa.org/schema/xfa-data/1.0/"
+ xfa:APIVersion="Acrobat:19.0.0" xfa:spec="2.0.2" style="font-size:12.0pt;font-family:Helvetica">
+<p dir="ltr">Please <b>check</b> the <span style="color:#FF0000;text-decoration:underline">totals</span></p>
+<p dir="ltr"><i>Thanks &amp; regards</i><br/>Jo</p>
+</body>`
+
+// TestParseRichText checks that ParseRichText returns the styled runs of rich text.
+func TestParseRichText(t *testing.T) {
+	runs, err := ParseRichText(richTextComment)
+	if err != nil {
+		t.Fatalf("ParseRichText failed. err=%v", err)
+	}
+	run := func(text string, style func(r *RichTextRun)) RichTextRun {
+		r := RichTextRun{Text: text, FontFamily: "Helvetica", FontSize: 12}
+		if style != nil {
+			style(&r)
+		}
+		return r
+	}
+	expected := []RichTextRun{
+		run("Please ", nil),
+		run("check", func(r *RichTextRun) { r.Bold = true }),
+		run(" the ", nil),
+		run("totals", func(r *RichTextRun) {
+			r.Underline = true
+			r.Color = color.RGBA{R: 0xff, A: 0xff}
+		}),
+		run("\n", nil),
+		run("Thanks & regards", func(r *RichTextRun) { r.Italic = true }),
+		run("\nJo", nil),
+	}
+	if len(runs) != len(expected) {
+		t.Fatalf("%d runs expected %d. runs=%v", len(runs), len(expected), runs)
+	}
+	for i, r := range runs {
+		if r != expected[i] {
+			t.Fatalf("run %d: %s color=%v expected %s color=%v", i, r, r.Color, expected[i],
+				expected[i].Color)
+		}
+	}
+}
+
+// TestExtractAnnotationTexts checks that ExtractAnnotationTexts returns the plain and rich text
+// of markup annotations.
+func TestExtractAnnotationTexts(t *testing.T) {
+	page := model.NewPdfPage()
+	page.MediaBox = &model.PdfRectangle{Llx: 0, Lly: 0, Urx: 612, Ury: 792}
+
+	freeText := model.NewPdfAnnotationFreeText()
+	freeText.Rect = core.MakeArrayFromFloats([]float64{100, 600, 300, 650})
+	freeText.Contents = core.MakeString("Please check the totals")
+	freeText.T = core.MakeString("Jo")
+	freeText.RC = core.MakeString(`<body><p>Please <b>check</b></p></body>`)
+	page.AddAnnotation(freeText.PdfAnnotation)
+
+	note := model.NewPdfAnnotationText()
+	note.Rect = core.MakeArrayFromFloats([]float64{10, 10, 30, 30})
+	note.Contents = core.MakeString("A sticky note")
+	page.AddAnnotation(note.PdfAnnotation)
+
+	link := model.NewPdfAnnotationLink()
+	link.Rect = core.MakeArrayFromFloats([]float64{10, 50, 30, 70})
+	page.AddAnnotation(link.PdfAnnotation)
+
+	texts, err := ExtractAnnotationTexts(page)
+	if err != nil {
+		t.Fatalf("ExtractAnnotationTexts failed. err=%v", err)
+	}
+	if len(texts) != 2 {
+		t.Fatalf("%d annotation texts expected 2. texts=%+v", len(texts), texts)
+	}
+	ft := texts[0]
+	if ft.Author != "Jo" || ft.Contents != "Please check the totals" ||
+		ft.BBox != (model.PdfRectangle{Llx: 100, Lly: 600, Urx: 300, Ury: 650}) {
+		t.Fatalf("incorrect FreeText annotation text %+v", ft)
+	}
+	if len(ft.RichText) != 2 || ft.RichText[0].Text != "Please " || ft.RichText[1].Text != "check" ||
+		!ft.RichText[1].Bold {
+		t.Fatalf("incorrect FreeText rich text %v", ft.RichText)
+	}
+	if texts[1].Contents != "A sticky note" || texts[1].RichText != nil {
+		t.Fatalf("incorrect Text annotation text %+v", texts[1])
+	}
+}
@@ -0,0 +1,269 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package extractor
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/unidoc/unipdf/v3/common"
+	"github.com/unidoc/unipdf/v3/contentstream"
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/internal/transform"
+)
+
+// RulingKind is the orientation of a Ruling.
+type RulingKind int
+
+// Ruling orientations.
+const (
+	RulingHorizontal RulingKind = iota // Horizontal ruling.
+	RulingVertical                     // Vertical ruling.
+)
+
+// String returns a string describing `k`.
+func (k RulingKind) String() string {
+	if k == RulingVertical {
+		return "vertical"
+	}
+	return "horizontal"
+}
+
+// Ruling is a horizontal or vertical line drawn on a page, such as the border of a table cell or
+// a line under a form field. Rulings are drawn by stroking straight path segments or by filling
+// thin rectangles. Coordinates are in the same space as the TextMark bounding boxes.
+type Ruling struct {
+	// Kind is the orientation of the ruling.
+	Kind RulingKind
+	// Primary is the y coordinate of a horizontal ruling or the x coordinate of a vertical ruling.
+	Primary float64
+	// Lo and Hi are the lowest and highest x coordinates of a horizontal ruling or y coordinates
+	// of a vertical ruling.
+	Lo, Hi float64
+}
+
+// String returns a string describing `r`.
+func (r Ruling) String() string {
+	return fmt.Sprintf("{Ruling: %s %.1f [%.1f, %.1f]}", r.Kind, r.Primary, r.Lo, r.Hi)
+}
+
+// Rulings returns the horizontal and vertical rulings drawn on the page. Touching and overlapping
+// collinear rulings are merged. Users building their own table detection or extracting form
+// layouts can use them.
+func (pt PageText) Rulings() []Ruling {
+	return mergeRulings(pt.rulings)
+}
+
+const (
+	// rulingTol is the maximum deviation from horizontal or vertical, in points, of rulings. It is
+	// also the maximum gap between collinear rulings that are merged.
+	rulingTol = 0.5
+	// rulingMaxWidth is the maximum width, in points, of filled rectangles that are rulings.
+	rulingMaxWidth = 2.0
+	// rulingMinLength is the minimum length, in points, of rulings.
+	rulingMinLength = 3.0
+)
+
+// pathBuilder builds the path that the path construction operators in a content stream describe.
+// The path's points are in device coordinates.
+type pathBuilder struct {
+	subpaths []subpath
+}
+
+// subpath is a sequence of connected straight line segments.
+type subpath struct {
+	points []transform.Point
+	closed bool
+}
+
+// handlePathOp updates `p` with path construction or painting operator `op` and returns the
+// rulings drawn by painting operators. `ctm` maps user space to device space.
+func (p *pathBuilder) handlePathOp(op *contentstream.ContentStreamOperation, ctm transform.Matrix) []Ruling {
+	var rulings []Ruling
+	switch op.Operand {
+	case "m", "l", "c", "v", "y":
+		// Curves aren't rulings so only their end points are kept, and they start new subpaths.
+		floats, err := core.GetNumbersAsFloat(op.Params)
+		if err != nil || len(floats) < 2 {
+			common.Log.Debug("ERROR: Bad path operator. op=%s err=%v", op, err)
+			return nil
+		}
+		x, y := ctm.Transform(floats[len(floats)-2], floats[len(floats)-1])
+		pt := transform.Point{X: x, Y: y}
+		if op.Operand == "l" && len(p.subpaths) > 0 {
+			sp := &p.subpaths[len(p.subpaths)-1]
+			sp.points = append(sp.points, pt)
+		} else {
+			p.subpaths = append(p.subpaths, subpath{points: []transform.Point{pt}})
+		}
+	case "re":
+		floats, err := core.GetNumbersAsFloat(op.Params)
+		if err != nil || len(floats) != 4 {
+			common.Log.Debug("ERROR: Bad re operator. op=%s err=%v", op, err)
+			return nil
+		}
+		x, y, w, h := floats[0], floats[1], floats[2], floats[3]
+		var points []transform.Point
+		for _, c := range [][2]float64{{x, y}, {x + w, y}, {x + w, y + h}, {x, y + h}} {
+			px, py := ctm.Transform(c[0], c[1])
+			points = append(points, transform.Point{X: px, Y: py})
+		}
+		p.subpaths = append(p.subpaths, subpath{points: points, closed: true})
+	case "h":
+		if len(p.subpaths) > 0 {
+			p.subpaths[len(p.subpaths)-1].closed = true
+		}
+	case "S", "s":
+		if op.Operand == "s" && len(p.subpaths) > 0 {
+			p.subpaths[len(p.subpaths)-1].closed = true
+		}
+		rulings = p.strokeRulings()
+		p.subpaths = nil
+	case "f", "F", "f*":
+		rulings = p.fillRulings()
+		p.subpaths = nil
+	case "B", "B*", "b", "b*":
+		if (op.Operand == "b" || op.Operand == "b*") && len(p.subpaths) > 0 {
+			p.subpaths[len(p.subpaths)-1].closed = true
+		}
+		rulings = append(p.strokeRulings(), p.fillRulings()...)
+		p.subpaths = nil
+	case "n":
+		p.subpaths = nil
+	case "sh":
+		// Shadings are painted in the current clipping region rather than the current path, and
+		// sh isn't allowed while a path is being constructed. A path that hasn't been painted by
+		// now, such as a clipping path with a missing n, is discarded so that it isn't stroked
+		// or filled by a later painting operator.
+		p.subpaths = nil
+	}
+	return rulings
+}
+
+// strokeRulings returns the rulings drawn by stroking the path in `p`. These are the horizontal
+// and vertical segments of the path.
+func (p *pathBuilder) strokeRulings() []Ruling {
+	var rulings []Ruling
+	for _, sp := range p.subpaths {
+		points := sp.points
+		if sp.closed && len(points) > 2 {
+			points = append(points[:len(points):len(points)], points[0])
+		}
+		for i := 1; i < len(points); i++ {
+			if r, ok := segmentRuling(points[i-1], points[i]); ok {
+				rulings = append(rulings, r)
+			}
+		}
+	}
+	return rulings
+}
+
+// fillRulings returns the rulings drawn by filling the path in `p`. These are the thin,
+// axis-aligned rectangles in the path.
+func (p *pathBuilder) fillRulings() []Ruling {
+	var rulings []Ruling
+	for _, sp := range p.subpaths {
+		llx, lly := math.Inf(1), math.Inf(1)
+		urx, ury := math.Inf(-1), math.Inf(-1)
+		for _, pt := range sp.points {
+			llx, lly = math.Min(llx, pt.X), math.Min(lly, pt.Y)
+			urx, ury = math.Max(urx, pt.X), math.Max(ury, pt.Y)
+		}
+		if !isAxisAlignedRect(sp.points, llx, lly, urx, ury) {
+			continue
+		}
+		w, h := urx-llx, ury-lly
+		switch {
+		case h <= rulingMaxWidth && w >= rulingMinLength && w > h:
+			rulings = append(rulings, Ruling{Kind: RulingHorizontal, Primary: (lly + ury) / 2,
+				Lo: llx, Hi: urx})
+		case w <= rulingMaxWidth && h >= rulingMinLength && h > w:
+			rulings = append(rulings, Ruling{Kind: RulingVertical, Primary: (llx + urx) / 2,
+				Lo: lly, Hi: ury})
+		}
+	}
+	return rulings
+}
+
+// isAxisAlignedRect returns true if `points` are the corners of the axis-aligned rectangle
+// (`llx`, `lly`), (`urx`, `ury`).
+func isAxisAlignedRect(points []transform.Point, llx, lly, urx, ury float64) bool {
+	if n := len(points); n == 5 && points[0] == points[4] {
+		points = points[:4]
+	}
+	if len(points) != 4 {
+		return false
+	}
+	for _, pt := range points {
+		onX := math.Abs(pt.X-llx) <= rulingTol || math.Abs(pt.X-urx) <= rulingTol
+		onY := math.Abs(pt.Y-lly) <= rulingTol || math.Abs(pt.Y-ury) <= rulingTol
+		if !onX || !onY {
+			return false
+		}
+	}
+	return true
+}
+
+// segmentRuling returns the ruling for the line segment from `p0` to `p1` if it is horizontal or
+// vertical.
+func segmentRuling(p0, p1 transform.Point) (Ruling, bool) {
+	dx, dy := math.Abs(p1.X-p0.X), math.Abs(p1.Y-p0.Y)
+	switch {
+	case dy <= rulingTol && dx >= rulingMinLength:
+		return Ruling{Kind: RulingHorizontal, Primary: (p0.Y + p1.Y) / 2,
+			Lo: math.Min(p0.X, p1.X), Hi: math.Max(p0.X, p1.X)}, true
+	case dx <= rulingTol && dy >= rulingMinLength:
+		return Ruling{Kind: RulingVertical, Primary: (p0.X + p1.X) / 2,
+			Lo: math.Min(p0.Y, p1.Y), Hi: math.Max(p0.Y, p1.Y)}, true
+	}
+	return Ruling{}, false
+}
+
+// mergeRulings returns `rulings` with touching and overlapping collinear rulings merged, sorted
+// by kind, primary coordinate and low coordinate.
+func mergeRulings(rulings []Ruling) []Ruling {
+	if len(rulings) == 0 {
+		return nil
+	}
+	sorted := make([]Ruling, len(rulings))
+	copy(sorted, rulings)
+	sort.Slice(sorted, func(i, j int) bool {
+		ri, rj := sorted[i], sorted[j]
+		if ri.Kind != rj.Kind {
+			return ri.Kind < rj.Kind
+		}
+		if ri.Primary != rj.Primary {
+			return ri.Primary < rj.Primary
+		}
+		return ri.Lo < rj.Lo
+	})
+
+	// Group rulings with close primary coordinates into lines and merge the overlapping rulings
+	// in each line.
+	var merged []Ruling
+	for i0 := 0; i0 < len(sorted); {
+		i1 := i0 + 1
+		for i1 < len(sorted) && sorted[i1].Kind == sorted[i0].Kind &&
+			sorted[i1].Primary-sorted[i1-1].Primary <= rulingTol {
+			i1++
+		}
+		line := sorted[i0:i1]
+		sort.Slice(line, func(i, j int) bool { return line[i].Lo < line[j].Lo })
+		cur := line[0]
+		for _, r := range line[1:] {
+			if r.Lo <= cur.Hi+rulingTol {
+				cur.Hi = math.Max(cur.Hi, r.Hi)
+				continue
+			}
+			merged = append(merged, cur)
+			cur = r
+		}
+		merged = append(merged, cur)
+		i0 = i1
+	}
+	return merged
+}
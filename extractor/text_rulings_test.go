@@ -0,0 +1,51 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package extractor
+
+import (
+	"math"
+	"testing"
+)
+
+// TestRulings checks that PageText.Rulings() finds stroked lines and thin filled rectangles.
+func TestRulings(t *testing.T) {
+	contents := `
+        0.5 w 10 700 m 200 700 l S
+        100 600 50 20 re S
+        10 500 100 1 re f
+        10 400 50 0.5 re f 60 400 40 0.5 re f
+        10 300 m 20 310 l S
+        10 200 100 50 re f
+        q 1 0 0 1 0 -100 cm 10 150 m 100 150 l S Q
+        10 100 m 100 100 l W n
+        10 80 m 100 80 l W /Sh0 sh 10 90 m 20 95 l S
+        BT /UniDocCourier 10 Tf 10 710 Td (Title) Tj ET`
+	pt := fragmentPageText(t, contents)
+	expected := []Ruling{
+		{RulingHorizontal, 50, 10, 100},
+		{RulingHorizontal, 400.25, 10, 100},
+		{RulingHorizontal, 500.5, 10, 110},
+		{RulingHorizontal, 600, 100, 150},
+		{RulingHorizontal, 620, 100, 150},
+		{RulingHorizontal, 700, 10, 200},
+		{RulingVertical, 100, 600, 620},
+		{RulingVertical, 150, 600, 620},
+	}
+	rulings := pt.Rulings()
+	if len(rulings) != len(expected) {
+		t.Fatalf("%d rulings expected %d. rulings=%v", len(rulings), len(expected), rulings)
+	}
+	for i, r := range rulings {
+		exp := expected[i]
+		if r.Kind != exp.Kind || math.Abs(r.Primary-exp.Primary) > 0.01 ||
+			math.Abs(r.Lo-exp.Lo) > 0.01 || math.Abs(r.Hi-exp.Hi) > 0.01 {
+			t.Fatalf("ruling %d: %s expected %s", i, r, exp)
+		}
+	}
+	if text := pt.Text(); text != "Title" {
+		t.Fatalf("text=%q", text)
+	}
+}
@@ -0,0 +1,61 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package extractor
+
+import (
+	"math"
+
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+// IsLikelyScanned returns true if the page of `pt` appears to be a scanned image with little or
+// no text. Such pages need OCR to extract their text.
+// This is a heuristic. A page is likely to be scanned if an image XObject covers at least
+// scanMinImageCoverage of it and its text covers at most scanMaxTextCoverage of it. It returns
+// false if the page size is unknown.
+func (pt PageText) IsLikelyScanned() bool {
+	pageArea := overlapArea(pt.mediaBox, pt.mediaBox)
+	if pageArea <= 0 {
+		return false
+	}
+	covered := false
+	for _, img := range pt.images {
+		if overlapArea(img.BBox, pt.mediaBox) >= scanMinImageCoverage*pageArea {
+			covered = true
+			break
+		}
+	}
+	if !covered {
+		return false
+	}
+	textArea := 0.0
+	for _, tm := range pt.marks {
+		if tm.text == "" || isTextSpace(tm.text) {
+			continue
+		}
+		textArea += overlapArea(tm.bbox, pt.mediaBox)
+	}
+	return textArea <= scanMaxTextCoverage*pageArea
+}
+
+const (
+	// scanMinImageCoverage is the minimum fraction of the page covered by the image of a scanned
+	// page.
+	scanMinImageCoverage = 0.8
+	// scanMaxTextCoverage is the maximum fraction of the page covered by the text of a scanned
+	// page.
+	scanMaxTextCoverage = 0.01
+)
+
+// overlapArea returns the area of the intersection of `a` and `b`.
+func overlapArea(a, b model.PdfRectangle) float64 {
+	w := math.Min(a.Urx, b.Urx) - math.Max(a.Llx, b.Llx)
+	h := math.Min(a.Ury, b.Ury) - math.Max(a.Lly, b.Lly)
+	if w <= 0 || h <= 0 {
+		return 0
+	}
+	return w * h
+}
@@ -0,0 +1,67 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package extractor
+
+import (
+	"testing"
+
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+// TestIsLikelyScanned checks that PageText.IsLikelyScanned() is true for pages that are covered
+// by an image and have little text, including images drawn in forms.
+func TestIsLikelyScanned(t *testing.T) {
+	resources := fragmentResources()
+	image, err := core.MakeStream([]byte{0}, core.NewRawEncoder())
+	if err != nil {
+		t.Fatalf("MakeStream failed. err=%v", err)
+	}
+	image.Set("Type", core.MakeName("XObject"))
+	image.Set("Subtype", core.MakeName("Image"))
+	image.Set("Width", core.MakeInteger(1))
+	image.Set("Height", core.MakeInteger(1))
+	image.Set("ColorSpace", core.MakeName("DeviceGray"))
+	image.Set("BitsPerComponent", core.MakeInteger(8))
+	if err := resources.SetXObjectByName("Im1", image); err != nil {
+		t.Fatalf("SetXObjectByName failed. err=%v", err)
+	}
+	xform := model.NewXObjectForm()
+	xform.BBox = core.MakeArrayFromFloats([]float64{0, 0, 612, 792})
+	xform.Resources = resources
+	err = xform.SetContentStream([]byte(`q 612 0 0 792 0 0 cm /Im1 Do Q`), core.NewRawEncoder())
+	if err != nil {
+		t.Fatalf("SetContentStream failed. err=%v", err)
+	}
+	if err := resources.SetXObjectFormByName("Fm1", xform); err != nil {
+		t.Fatalf("SetXObjectFormByName failed. err=%v", err)
+	}
+
+	for _, test := range []struct {
+		contents string
+		scanned  bool
+	}{
+		{`q 612 0 0 792 0 0 cm /Im1 Do Q`, true},
+		{`/Fm1 Do`, true},
+		{`q 612 0 0 792 0 0 cm /Im1 Do Q BT /UniDocCourier 10 Tf 10 10 Td (Page 1) Tj ET`, true},
+		{`q 200 0 0 200 100 300 cm /Im1 Do Q`, false},
+		{`BT /UniDocCourier 10 Tf 10 700 Td (Hello) Tj ET`, false},
+		{`q 612 0 0 792 0 0 cm /Im1 Do Q
+          BT /UniDocCourier 40 Tf 40 TL 10 700 Td
+          (A heading in large text) Tj T* (over a full page image) Tj T* (such as a poster) Tj
+          T* (or a slide) Tj ET`, false},
+	} {
+		e := NewFromContents(test.contents, resources, fragmentMediaBox)
+		pt, _, _, err := e.ExtractPageText()
+		if err != nil {
+			t.Fatalf("ExtractPageText failed. err=%v", err)
+		}
+		if scanned := pt.IsLikelyScanned(); scanned != test.scanned {
+			t.Fatalf("contents=%q: IsLikelyScanned=%t expected %t", test.contents, scanned,
+				test.scanned)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package extractor
+
+import (
+	"math"
+	"strings"
+
+	"github.com/unidoc/unipdf/v3/internal/transform"
+)
+
+// ScriptPosition tells whether text was drawn as a superscript or subscript.
+type ScriptPosition int
+
+const (
+	// ScriptNone is text drawn on the baseline.
+	ScriptNone ScriptPosition = iota
+	// ScriptSuper is text raised above the baseline by the text rise (Ts).
+	ScriptSuper
+	// ScriptSub is text lowered below the baseline by the text rise (Ts).
+	ScriptSub
+)
+
+// String returns a string describing `s`.
+func (s ScriptPosition) String() string {
+	switch s {
+	case ScriptSuper:
+		return "superscript"
+	case ScriptSub:
+		return "subscript"
+	}
+	return "none"
+}
+
+// scriptMinRise is the minimum text rise of superscripts and subscripts as a fraction of the font
+// size. Smaller rises are used to adjust the positions of glyphs, not to draw scripts.
+const scriptMinRise = 0.1
+
+// scriptPosition returns the script position of text drawn with text state `state`.
+func scriptPosition(state *textState) ScriptPosition {
+	rise := scriptMinRise * math.Abs(state.tfs)
+	switch {
+	case rise == 0:
+		return ScriptNone
+	case state.trise >= rise:
+		return ScriptSuper
+	case state.trise <= -rise:
+		return ScriptSub
+	}
+	return ScriptNone
+}
+
+// superscriptRunes and subscriptRunes map characters to their Unicode superscript and subscript
+// forms.
+var (
+	superscriptRunes = map[rune]rune{
+		'0': '⁰', '1': '¹', '2': '²', '3': '³', '4': '⁴',
+		'5': '⁵', '6': '⁶', '7': '⁷', '8': '⁸', '9': '⁹',
+		'+': '⁺', '-': '⁻', '−': '⁻', '=': '⁼', '(': '⁽', ')': '⁾',
+		'i': 'ⁱ', 'n': 'ⁿ',
+	}
+	subscriptRunes = map[rune]rune{
+		'0': '₀', '1': '₁', '2': '₂', '3': '₃', '4': '₄',
+		'5': '₅', '6': '₆', '7': '₇', '8': '₈', '9': '₉',
+		'+': '₊', '-': '₋', '−': '₋', '=': '₌', '(': '₍', ')': '₎',
+		'a': 'ₐ', 'e': 'ₑ', 'h': 'ₕ', 'k': 'ₖ', 'l': 'ₗ', 'm': 'ₘ', 'n': 'ₙ',
+		'o': 'ₒ', 'p': 'ₚ', 's': 'ₛ', 't': 'ₜ', 'x': 'ₓ',
+	}
+)
+
+// unicodeScriptText returns `text` with its characters replaced by their Unicode forms for script
+// position `script`. It returns false if `text` is not a script or any of its characters has no
+// such form.
+func unicodeScriptText(text string, script ScriptPosition) (string, bool) {
+	var runes map[rune]rune
+	switch script {
+	case ScriptSuper:
+		runes = superscriptRunes
+	case ScriptSub:
+		runes = subscriptRunes
+	default:
+		return text, false
+	}
+	var sb strings.Builder
+	for _, r := range text {
+		s, ok := runes[r]
+		if !ok {
+			return text, false
+		}
+		sb.WriteRune(s)
+	}
+	return sb.String(), sb.Len() > 0
+}
+
+// toUnicodeScript replaces the text of `tm` with its Unicode superscript or subscript form if
+// there is one. The mark is then moved onto the baseline, which is `rise` away in device
+// coordinates, for ordering, so that it is put in the same line and word as the text it is
+// attached to. Its bounding box is not changed.
+func (tm *textMark) toUnicodeScript(rise transform.Point) {
+	text, ok := unicodeScriptText(tm.text, tm.script)
+	if !ok {
+		return
+	}
+	tm.text = text
+	d := rise.Rotate(tm.trm.Angle())
+	if isMirrored(tm.trm) {
+		d.Y = -d.Y
+	}
+	tm.orientedStart.Y -= d.Y
+	tm.orientedEnd.Y -= d.Y
+}
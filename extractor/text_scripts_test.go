@@ -0,0 +1,75 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package extractor
+
+import (
+	"testing"
+)
+
+// TestUnicodeScripts checks that TextMark.Script is set for text drawn with a text rise and that
+// ExtractOptions.UnicodeScripts replaces scripts that have Unicode forms with those forms in the
+// line of the text they are attached to.
+func TestUnicodeScripts(t *testing.T) {
+	contents := `
+        BT
+        /UniDocHelvetica 10 Tf 10 700 Td (E = mc) Tj
+        /UniDocHelvetica 7 Tf 4 Ts (2) Tj
+        /UniDocHelvetica 10 Tf 0 Ts ( and H) Tj
+        /UniDocHelvetica 7 Tf -2 Ts (2) Tj
+        /UniDocHelvetica 10 Tf 0 Ts (O) Tj
+        ET
+        BT
+        /UniDocHelvetica 10 Tf 10 650 Td (See note) Tj
+        /UniDocHelvetica 7 Tf 4 Ts (b) Tj
+        ET`
+	for _, test := range []struct {
+		unicode bool
+		lines   []string
+	}{
+		{false, []string{"2", "E = mc and H O", "2", "b", "See note"}},
+		{true, []string{"E = mc² and H₂O", "b", "See note"}},
+	} {
+		e := NewFromContents(contents, fragmentResources(), fragmentMediaBox)
+		e.options.UnicodeScripts = test.unicode
+		pt, _, _, err := e.ExtractPageText()
+		if err != nil {
+			t.Fatalf("ExtractPageText failed. err=%v", err)
+		}
+		lines := pt.Lines()
+		if len(lines) != len(test.lines) {
+			t.Fatalf("UnicodeScripts=%t: %d lines expected %d. text=%q", test.unicode,
+				len(lines), len(test.lines), pt.Text())
+		}
+		for i, tl := range lines {
+			if tl.Text != test.lines[i] {
+				t.Fatalf("UnicodeScripts=%t: line %d=%q expected %q", test.unicode, i, tl.Text,
+					test.lines[i])
+			}
+		}
+
+		expected := map[string]ScriptPosition{
+			"2": ScriptSuper, "²": ScriptSuper, "₂": ScriptSub, "b": ScriptSuper, "m": ScriptNone,
+		}
+		subscript := false
+		for _, tm := range pt.Marks().Elements() {
+			if tm.Text == "2" && tm.BBox.Lly < 700 {
+				subscript = true
+				if tm.Script != ScriptSub {
+					t.Fatalf("UnicodeScripts=%t: %s Script=%s expected %s", test.unicode, tm,
+						tm.Script, ScriptSub)
+				}
+				continue
+			}
+			if script, ok := expected[tm.Text]; ok && tm.Script != script {
+				t.Fatalf("UnicodeScripts=%t: %s Script=%s expected %s", test.unicode, tm,
+					tm.Script, script)
+			}
+		}
+		if subscript == test.unicode {
+			t.Fatalf("UnicodeScripts=%t: subscript 2 found=%t", test.unicode, subscript)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package extractor
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+// Sentence is a sentence in the extracted text of a page.
+type Sentence struct {
+	// Text is the text of the sentence. It is the substring Text()[Offset:Offset+len(Text)] of the
+	// page's extracted text so it may contain line breaks.
+	Text string
+	// Offset is the offset of the start of the sentence in the page's extracted text.
+	Offset int
+	// Marks are the TextMarks of the sentence.
+	Marks *TextMarkArray
+	// BBox is the bounding box of the sentence's text.
+	BBox model.PdfRectangle
+}
+
+// String returns a string describing `s`.
+func (s Sentence) String() string {
+	b := s.BBox
+	return fmt.Sprintf("{Sentence: %d (%5.1f, %5.1f) (%5.1f, %5.1f) %q}",
+		s.Offset, b.Llx, b.Lly, b.Urx, b.Ury, s.Text)
+}
+
+// SentenceBoundary returns true if a sentence in `text` ends at offset `end`. It is called for
+// each offset just after a run of sentence terminating punctuation (. ! ?) and the closing quotes
+// and brackets that follow it.
+type SentenceBoundary func(text string, end int) bool
+
+// Sentences returns the sentences in the extracted text of `pt` in reading order. Sentences are
+// split with DefaultSentenceBoundary.
+func (pt PageText) Sentences() []Sentence {
+	return pt.SentencesFunc(DefaultSentenceBoundary)
+}
+
+// SentencesFunc returns the sentences in the extracted text of `pt` in reading order, using
+// `isBoundary` to decide where sentences end. Text after the last sentence boundary is returned
+// as the last sentence.
+func (pt PageText) SentencesFunc(isBoundary SentenceBoundary) []Sentence {
+	text := pt.viewText
+	var sentences []Sentence
+	addSentence := func(start, end int) {
+		s := strings.TrimLeftFunc(text[start:end], unicode.IsSpace)
+		start = end - len(s)
+		s = strings.TrimRightFunc(s, unicode.IsSpace)
+		if s == "" {
+			return
+		}
+		end = start + len(s)
+		marks := rangeMarks(pt.viewMarks, start, end)
+		bbox, _ := marksBBox(marks)
+		sentences = append(sentences, Sentence{
+			Text:   s,
+			Offset: start,
+			Marks:  &TextMarkArray{marks: marks},
+			BBox:   bbox,
+		})
+	}
+
+	start := 0
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		i += size
+		if !isSentenceTerminator(r) {
+			continue
+		}
+		end := i + sentenceEndLen(text[i:])
+		if isBoundary(text, end) {
+			addSentence(start, end)
+			start = end
+		}
+		i = end
+	}
+	addSentence(start, len(text))
+	return sentences
+}
+
+// DefaultSentenceBoundary is the SentenceBoundary used by PageText.Sentences(). A sentence ends
+// after terminating punctuation that is followed by the end of the text, or by whitespace and a
+// character that is not a lower case letter. Periods in numbers like "3.14" and after common
+// abbreviations like "Dr." and "e.g." and initials like "J." don't end sentences.
+func DefaultSentenceBoundary(text string, end int) bool {
+	rest := strings.TrimLeftFunc(text[end:], unicode.IsSpace)
+	if rest == "" {
+		return true
+	}
+	if len(rest) == len(text[end:]) {
+		// Not followed by whitespace.
+		return false
+	}
+	if r, _ := utf8.DecodeRuneInString(rest); unicode.IsLower(r) {
+		return false
+	}
+
+	// The punctuation and the word before it.
+	sentence := strings.TrimRightFunc(text[:end], isSentenceCloser)
+	word := strings.TrimRightFunc(sentence, isSentenceTerminator)
+	if sentence[len(word):] != "." {
+		return true
+	}
+	if i := strings.LastIndexFunc(word, unicode.IsSpace); i >= 0 {
+		word = word[i+1:]
+	}
+	word = strings.TrimLeftFunc(word, isSentenceOpener)
+	if utf8.RuneCountInString(word) == 1 {
+		if r, _ := utf8.DecodeRuneInString(word); unicode.IsUpper(r) {
+			return false
+		}
+	}
+	return !sentenceAbbreviations[strings.ToLower(word)]
+}
+
+// sentenceAbbreviations are common abbreviations that are followed by a period that doesn't end
+// a sentence. The periods at the ends of the abbreviations are not included.
+var sentenceAbbreviations = map[string]bool{
+	"mr": true, "mrs": true, "ms": true, "dr": true, "prof": true, "sr": true, "jr": true,
+	"st": true, "mt": true, "no": true, "vs": true, "etc": true, "fig": true, "figs": true,
+	"eq": true, "vol": true, "p": true, "pp": true, "e.g": true, "i.e": true, "cf": true,
+	"inc": true, "ltd": true, "co": true, "corp": true, "approx": true, "dept": true,
+}
+
+// isSentenceTerminator returns true if `r` is punctuation that can end a sentence.
+func isSentenceTerminator(r rune) bool {
+	return r == '.' || r == '!' || r == '?'
+}
+
+// isSentenceCloser returns true if `r` is a closing quote or bracket that can follow the
+// punctuation at the end of a sentence.
+func isSentenceCloser(r rune) bool {
+	return strings.ContainsRune(`)]"'’”»`, r)
+}
+
+// isSentenceOpener returns true if `r` is an opening quote or bracket.
+func isSentenceOpener(r rune) bool {
+	return strings.ContainsRune(`([“‘«"'`, r)
+}
+
+// sentenceEndLen returns the length of the run of terminating punctuation and closing quotes and
+// brackets at the start of `text`.
+func sentenceEndLen(text string) int {
+	return len(text) - len(strings.TrimLeftFunc(text, func(r rune) bool {
+		return isSentenceTerminator(r) || isSentenceCloser(r)
+	}))
+}
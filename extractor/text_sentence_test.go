@@ -0,0 +1,63 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package extractor
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestSentences checks that PageText.Sentences() splits the page text into sentences and maps
+// them back to their marks.
+func TestSentences(t *testing.T) {
+	contents := `
+        BT
+        /UniDocCourier 10 Tf
+        10 700 Td (Dr. Smith paid $3.14 for it. Was it worth it?) Tj
+        0 -20 Td (Yes! See Fig. 2 and the notes, e.g. the) Tj
+        0 -20 Td (appendix by J. Jones) Tj
+        ET`
+	pt := fragmentPageText(t, contents)
+	expected := []string{
+		"Dr. Smith paid $3.14 for it.",
+		"Was it worth it?",
+		"Yes!",
+		"See Fig. 2 and the notes, e.g. the\nappendix by J. Jones",
+	}
+	sentences := pt.Sentences()
+	if len(sentences) != len(expected) {
+		t.Fatalf("%d sentences expected %d. sentences=%v", len(sentences), len(expected),
+			sentences)
+	}
+	text := pt.Text()
+	for i, s := range sentences {
+		if s.Text != expected[i] {
+			t.Fatalf("sentence %d: %q expected %q", i, s.Text, expected[i])
+		}
+		if text[s.Offset:s.Offset+len(s.Text)] != s.Text {
+			t.Fatalf("sentence %d: inconsistent offset %d", i, s.Offset)
+		}
+		var parts []string
+		for _, tm := range s.Marks.Elements() {
+			parts = append(parts, tm.Text)
+		}
+		if strings.Join(parts, "") != s.Text {
+			t.Fatalf("sentence %d: marks %q don't match text %q", i, parts, s.Text)
+		}
+	}
+	// The last sentence spans two lines.
+	if b := sentences[3].BBox; b.Ury-b.Lly < 20 {
+		t.Fatalf("incorrect bbox %+v", b)
+	}
+
+	// Split at every period.
+	sentences = pt.SentencesFunc(func(text string, end int) bool {
+		return text[end-1] == '.'
+	})
+	if len(sentences) != 8 {
+		t.Fatalf("%d sentences expected 8. sentences=%v", len(sentences), sentences)
+	}
+}
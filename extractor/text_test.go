@@ -9,18 +9,21 @@ import (
 	"encoding/json"
 	"flag"
 	"fmt"
+	"image/color"
 	"io"
 	"io/ioutil"
 	"math"
 	"os"
 	"path"
 	"path/filepath"
+	"reflect"
 	"regexp"
 	"sort"
 	"strings"
 	"testing"
 
 	"github.com/unidoc/unipdf/v3/common"
+	"github.com/unidoc/unipdf/v3/core"
 	"github.com/unidoc/unipdf/v3/creator"
 	"github.com/unidoc/unipdf/v3/internal/transform"
 	"github.com/unidoc/unipdf/v3/model"
@@ -109,6 +112,34 @@ func TestTextExtractionFragments(t *testing.T) {
         0 -10 Td
         (Doink)Tj
         ET
+        `,
+			text: "Hello World!\nDoink",
+		},
+		{
+			name: "text after unmatched ET",
+			contents: `
+        BT
+        /UniDocCourier 24 Tf
+        100 200 Td
+        (Hello) Tj
+        ET
+        ET
+        (Bye) Tj
+        BT
+        100 100 Td
+        (Doink) Tj
+        ET
+        `,
+			text: "Hello\nDoink\nBye",
+		},
+		{
+			name: "no ET",
+			contents: `
+        BT
+        /UniDocCourier 24 Tf
+        (Hello World!)Tj
+        0 -10 Td
+        (Doink)Tj
         `,
 			text: "Hello World!\nDoink",
 		},
@@ -139,6 +170,32 @@ func TestTextExtractionFragments(t *testing.T) {
 	}
 }
 
+// fragmentResources returns the mock resources used by the content stream fragments in the tests.
+func fragmentResources() *model.PdfPageResources {
+	resources := model.NewPdfPageResources()
+	courier := model.NewStandard14FontMustCompile(model.CourierName)
+	helvetica := model.NewStandard14FontMustCompile(model.HelveticaName)
+	helveticaBold := model.NewStandard14FontMustCompile(model.HelveticaBoldName)
+	resources.SetFontByName("UniDocHelvetica", helvetica.ToPdfObject())
+	resources.SetFontByName("UniDocHelveticaBold", helveticaBold.ToPdfObject())
+	resources.SetFontByName("UniDocCourier", courier.ToPdfObject())
+	return resources
+}
+
+// fragmentMediaBox is the MediaBox of the page that content stream fragments are drawn on.
+var fragmentMediaBox = model.PdfRectangle{Llx: 0, Lly: 0, Urx: 612, Ury: 792}
+
+// fragmentPageText returns the PageText extracted from content stream fragment `contents` which
+// is rendered with fragmentResources().
+func fragmentPageText(t *testing.T, contents string) *PageText {
+	e := NewFromContents(contents, fragmentResources(), fragmentMediaBox)
+	pt, _, _, err := e.ExtractPageText()
+	if err != nil {
+		t.Fatalf("ExtractPageText failed. contents=%q err=%v", contents, err)
+	}
+	return pt
+}
+
 // TestTextExtractionFiles tests text extraction on a set of PDF files.
 // It checks for the existence of specified strings of words on specified pages.
 // We currently only check within lines as our line order is still improving.
@@ -179,6 +236,1551 @@ func TestTermMarksFiles(t *testing.T) {
 	testTermMarksFiles(t)
 }
 
+// TestMaxMarks checks that ExtractOptions.MaxMarks limits the number of marks extracted.
+func TestMaxMarks(t *testing.T) {
+	contents := `BT /UniDocCourier 24 Tf (Hello) Tj [(World) -200 (!)] TJ ET`
+	for _, test := range []struct {
+		maxMarks int
+		err      error
+	}{
+		{0, nil},
+		{11, nil},
+		{10, ErrMaxMarks},
+		{3, ErrMaxMarks},
+	} {
+		e := Extractor{resources: fragmentResources(), contents: contents,
+			options: ExtractOptions{MaxMarks: test.maxMarks}}
+		_, _, _, err := e.ExtractPageText()
+		if err != test.err {
+			t.Fatalf("MaxMarks=%d: got err=%v expected %v", test.maxMarks, err, test.err)
+		}
+	}
+}
+
+// TestMissingCharMetrics checks that text in a font without metrics is extracted with estimated
+// widths and that the characters are counted as misses.
+func TestMissingCharMetrics(t *testing.T) {
+	fontDict := core.MakeDict()
+	fontDict.Set("Type", core.MakeName("Font"))
+	fontDict.Set("Subtype", core.MakeName("TrueType"))
+	fontDict.Set("BaseFont", core.MakeName("NoMetrics"))
+	fontDict.Set("Encoding", core.MakeName("WinAnsiEncoding"))
+	resources := fragmentResources()
+	resources.SetFontByName("NoMetrics", fontDict)
+
+	contents := `BT /NoMetrics 10 Tf 10 700 Td (Hello World) Tj ET`
+	e := Extractor{resources: resources, contents: contents}
+	pt, _, numMisses, err := e.ExtractPageText()
+	if err != nil {
+		t.Fatalf("ExtractPageText failed. err=%v", err)
+	}
+	if text := pt.Text(); text != "Hello World" {
+		t.Fatalf("text=%q expected %q", text, "Hello World")
+	}
+	// The text is decoded so the missing metrics aren't decoding misses.
+	if numMisses != 0 {
+		t.Fatalf("numMisses=%d expected 0", numMisses)
+	}
+	stats := pt.FontDecodeStats()
+	if len(stats) != 1 || stats[0].NumMetricMisses != len("Hello World") {
+		t.Fatalf("stats=%+v expected %d metric misses", stats, len("Hello World"))
+	}
+	marks := pt.Marks().Elements()
+	for i := 1; i < len(marks); i++ {
+		if marks[i].BBox.Llx <= marks[i-1].BBox.Llx {
+			t.Fatalf("marks don't advance. %s %s", marks[i-1], marks[i])
+		}
+	}
+}
+
+// TestUseCropBox checks that ExtractOptions.UseCropBox measures text positions from the corner of
+// the page's CropBox.
+func TestUseCropBox(t *testing.T) {
+	page := model.NewPdfPage()
+	page.MediaBox = &model.PdfRectangle{Llx: 0, Lly: 0, Urx: 612, Ury: 792}
+	page.CropBox = &model.PdfRectangle{Llx: 36, Lly: 72, Urx: 576, Ury: 720}
+	page.Resources = fragmentResources()
+	contents := `BT /UniDocCourier 10 Tf 100 200 Td (Hello) Tj ET`
+	if err := page.SetContentStreams([]string{contents}, core.NewRawEncoder()); err != nil {
+		t.Fatalf("SetContentStreams failed. err=%v", err)
+	}
+	for _, test := range []struct {
+		useCropBox bool
+		llx, lly   float64
+	}{
+		{false, 100, 200},
+		{true, 64, 128},
+	} {
+		e, err := NewWithOptions(page, &ExtractOptions{UseCropBox: test.useCropBox})
+		if err != nil {
+			t.Fatalf("NewWithOptions failed. err=%v", err)
+		}
+		pt, _, _, err := e.ExtractPageText()
+		if err != nil {
+			t.Fatalf("ExtractPageText failed. err=%v", err)
+		}
+		bbox, ok := pt.Marks().BBox()
+		if !ok {
+			t.Fatalf("UseCropBox=%t: no marks", test.useCropBox)
+		}
+		if math.Abs(bbox.Llx-test.llx) > 0.01 || math.Abs(bbox.Lly-test.lly) > 3 {
+			t.Fatalf("UseCropBox=%t: bbox=%+v expected (%g, %g)", test.useCropBox, bbox,
+				test.llx, test.lly)
+		}
+	}
+}
+
+// TestCoordinateTransform checks that ExtractOptions.CoordinateTransform transforms text positions
+// and that text is in the same order when the transform flips the y axis.
+func TestCoordinateTransform(t *testing.T) {
+	contents := `
+        BT /UniDocCourier 10 Tf 10 700 Td (Hello) Tj 0 -20 Td (World) Tj ET
+        10 600 m 100 600 l S`
+	for _, test := range []struct {
+		transform []float64
+		hello     model.PdfRectangle
+		ruling    float64
+	}{
+		{nil, model.PdfRectangle{Llx: 10, Lly: 700, Urx: 40, Ury: 710}, 600},
+		// Pixels at 144 DPI with the origin at the top left of the page.
+		{[]float64{2, 0, 0, -2, 0, 1584}, model.PdfRectangle{Llx: 20, Lly: 164, Urx: 80, Ury: 184}, 384},
+	} {
+		e := NewFromContents(contents, fragmentResources(), fragmentMediaBox)
+		e.options.CoordinateTransform = test.transform
+		pt, _, _, err := e.ExtractPageText()
+		if err != nil {
+			t.Fatalf("ExtractPageText failed. err=%v", err)
+		}
+		if text := pt.Text(); text != "Hello\nWorld" {
+			t.Fatalf("transform=%v: text=%q expected %q", test.transform, text, "Hello\nWorld")
+		}
+		bbox := pt.Lines()[0].BBox
+		if math.Abs(bbox.Llx-test.hello.Llx) > 0.01 || math.Abs(bbox.Urx-test.hello.Urx) > 0.01 ||
+			math.Abs(bbox.Lly-test.hello.Lly) > 3 || math.Abs(bbox.Ury-test.hello.Ury) > 3 {
+			t.Fatalf("transform=%v: bbox=%+v expected %+v", test.transform, bbox, test.hello)
+		}
+		rulings := pt.Rulings()
+		if len(rulings) != 1 || math.Abs(rulings[0].Primary-test.ruling) > 0.01 {
+			t.Fatalf("transform=%v: rulings=%v expected 1 at %g", test.transform, rulings,
+				test.ruling)
+		}
+	}
+}
+
+// TestInheritedFontResources checks that fonts that are not in a page's resources are found in
+// the resources of the page's ancestors in the page tree.
+func TestInheritedFontResources(t *testing.T) {
+	pagesResources := model.NewPdfPageResources()
+	helvetica := model.NewStandard14FontMustCompile(model.HelveticaName)
+	pagesResources.SetFontByName("F1", helvetica.ToPdfObject())
+	root := core.MakeDict()
+	root.Set("Type", core.MakeName("Pages"))
+	root.Set("Resources", pagesResources.ToPdfObject())
+	pages := core.MakeDict()
+	pages.Set("Type", core.MakeName("Pages"))
+	pages.Set("Parent", root)
+
+	page := model.NewPdfPage()
+	page.MediaBox = &model.PdfRectangle{Llx: 0, Lly: 0, Urx: 612, Ury: 792}
+	page.Parent = pages
+	page.Resources = fragmentResources()
+	contents := `BT /F1 10 Tf 10 700 Td (Hello) Tj ET`
+	if err := page.SetContentStreams([]string{contents}, core.NewRawEncoder()); err != nil {
+		t.Fatalf("SetContentStreams failed. err=%v", err)
+	}
+	e, err := New(page)
+	if err != nil {
+		t.Fatalf("New failed. err=%v", err)
+	}
+	pt, _, _, err := e.ExtractPageText()
+	if err != nil {
+		t.Fatalf("ExtractPageText failed. err=%v", err)
+	}
+	if text := pt.Text(); text != "Hello" {
+		t.Fatalf("text=%q expected %q", text, "Hello")
+	}
+	for _, tm := range pt.Marks().Elements() {
+		if tm.Font == nil || tm.Font.BaseFont() != string(model.HelveticaName) {
+			t.Fatalf("%s: font=%v expected %s", tm, tm.Font, model.HelveticaName)
+		}
+	}
+}
+
+// TestExtractorReset checks that an Extractor that is Reset with another page extracts that page's
+// text, including forms with the same names as forms on the previous page, and keeps its font
+// cache.
+func TestExtractorReset(t *testing.T) {
+	newPage := func(text string) *model.PdfPage {
+		resources := fragmentResources()
+		xform := model.NewXObjectForm()
+		xform.BBox = core.MakeArrayFromFloats([]float64{0, 0, 612, 792})
+		err := xform.SetContentStream([]byte(`BT /UniDocCourier 10 Tf 10 700 Td (`+text+`) Tj ET`),
+			core.NewRawEncoder())
+		if err != nil {
+			t.Fatalf("SetContentStream failed. err=%v", err)
+		}
+		if err := resources.SetXObjectFormByName("Fm1", xform); err != nil {
+			t.Fatalf("SetXObjectFormByName failed. err=%v", err)
+		}
+		page := model.NewPdfPage()
+		page.MediaBox = &model.PdfRectangle{Llx: 0, Lly: 0, Urx: 612, Ury: 792}
+		page.Resources = resources
+		if err := page.SetContentStreams([]string{`/Fm1 Do`}, core.NewRawEncoder()); err != nil {
+			t.Fatalf("SetContentStreams failed. err=%v", err)
+		}
+		return page
+	}
+
+	e, err := New(newPage("First"))
+	if err != nil {
+		t.Fatalf("New failed. err=%v", err)
+	}
+	cache := e.fontCache
+	for _, text := range []string{"First", "Second", "Third"} {
+		if text != "First" {
+			if err := e.Reset(newPage(text)); err != nil {
+				t.Fatalf("Reset failed. err=%v", err)
+			}
+		}
+		pt, _, _, err := e.ExtractPageText()
+		if err != nil {
+			t.Fatalf("ExtractPageText failed. err=%v", err)
+		}
+		if pt.Text() != text {
+			t.Fatalf("text=%q expected %q", pt.Text(), text)
+		}
+		if e.fontCache != cache {
+			t.Fatalf("font cache not kept")
+		}
+	}
+}
+
+// TestWidgetAppearances checks that ExtractOptions.IncludeWidgetAppearances adds the text of
+// visible widget annotation appearance streams at the annotations' positions.
+func TestWidgetAppearances(t *testing.T) {
+	page := model.NewPdfPage()
+	page.MediaBox = &model.PdfRectangle{Llx: 0, Lly: 0, Urx: 612, Ury: 792}
+	page.Resources = fragmentResources()
+	contents := `BT /UniDocCourier 10 Tf 100 700 Td (Name:) Tj ET`
+	if err := page.SetContentStreams([]string{contents}, core.NewRawEncoder()); err != nil {
+		t.Fatalf("SetContentStreams failed. err=%v", err)
+	}
+	addWidget := func(value string, rect []float64, flags int64) {
+		ap, err := core.MakeStream([]byte("BT /UniDocCourier 10 Tf 2 2 Td ("+value+") Tj ET"), nil)
+		if err != nil {
+			t.Fatalf("MakeStream failed. err=%v", err)
+		}
+		ap.PdfObjectDictionary.Set("Subtype", core.MakeName("Form"))
+		ap.PdfObjectDictionary.Set("BBox", core.MakeArrayFromFloats([]float64{0, 0, 100, 10}))
+		apDict := core.MakeDict()
+		apDict.Set("N", ap)
+		widget := model.NewPdfAnnotationWidget()
+		widget.Rect = core.MakeArrayFromFloats(rect)
+		widget.AP = apDict
+		widget.F = core.MakeInteger(flags)
+		page.AddAnnotation(widget.PdfAnnotation)
+	}
+	// The appearance stream is scaled by 2 to fit the annotation rectangle.
+	addWidget("Alice", []float64{200, 690, 400, 710}, 0)
+	addWidget("Hidden", []float64{200, 600, 400, 620}, 2)
+
+	for _, include := range []bool{false, true} {
+		e, err := NewWithOptions(page, &ExtractOptions{IncludeWidgetAppearances: include})
+		if err != nil {
+			t.Fatalf("NewWithOptions failed. err=%v", err)
+		}
+		pt, _, _, err := e.ExtractPageText()
+		if err != nil {
+			t.Fatalf("ExtractPageText failed. err=%v", err)
+		}
+		text := pt.Text()
+		if strings.Contains(text, "Hidden") {
+			t.Fatalf("include=%t: hidden widget extracted. text=%q", include, text)
+		}
+		if !include {
+			if text != "Name:" {
+				t.Fatalf("include=%t: text=%q", include, text)
+			}
+			continue
+		}
+		start := strings.Index(text, "Alice")
+		if start < 0 {
+			t.Fatalf("include=%t: widget value not extracted. text=%q", include, text)
+		}
+		bbox, ok := pt.BBoxForRange(start, start+len("Alice"))
+		if !ok || math.Abs(bbox.Llx-204) > 0.01 || math.Abs(bbox.Urx-264) > 0.01 {
+			t.Fatalf("include=%t: incorrect bbox=%+v", include, bbox)
+		}
+	}
+}
+
+// TestWidgetFormNames checks that forms with the same name in the resources of different widget
+// appearance streams, as in Acrobat's /FRM appearance layout, are extracted separately at their
+// widgets' positions, and that a form drawn twice on a page is extracted at both positions.
+func TestWidgetFormNames(t *testing.T) {
+	makeForm := func(contents string, resources *model.PdfPageResources) *core.PdfObjectStream {
+		form, err := core.MakeStream([]byte(contents), nil)
+		if err != nil {
+			t.Fatalf("MakeStream failed. err=%v", err)
+		}
+		form.PdfObjectDictionary.Set("Subtype", core.MakeName("Form"))
+		form.PdfObjectDictionary.Set("BBox", core.MakeArrayFromFloats([]float64{0, 0, 100, 10}))
+		if resources != nil {
+			form.PdfObjectDictionary.Set("Resources", resources.ToPdfObject())
+		}
+		return form
+	}
+
+	page := model.NewPdfPage()
+	page.MediaBox = &model.PdfRectangle{Llx: 0, Lly: 0, Urx: 612, Ury: 792}
+	page.Resources = fragmentResources()
+	page.Resources.SetXObjectByName("Label",
+		makeForm("BT /UniDocCourier 10 Tf 0 0 Td (Label) Tj ET", nil))
+	contents := `q 1 0 0 1 10 700 cm /Label Do Q q 1 0 0 1 10 500 cm /Label Do Q`
+	if err := page.SetContentStreams([]string{contents}, core.NewRawEncoder()); err != nil {
+		t.Fatalf("SetContentStreams failed. err=%v", err)
+	}
+	for _, w := range []struct {
+		value string
+		rect  []float64
+	}{
+		{"Alice", []float64{200, 700, 300, 710}},
+		{"Bob", []float64{200, 600, 300, 610}},
+	} {
+		resources := fragmentResources()
+		resources.SetXObjectByName("FRM",
+			makeForm("BT /UniDocCourier 10 Tf 0 0 Td ("+w.value+") Tj ET", nil))
+		apDict := core.MakeDict()
+		apDict.Set("N", makeForm("/FRM Do", resources))
+		widget := model.NewPdfAnnotationWidget()
+		widget.Rect = core.MakeArrayFromFloats(w.rect)
+		widget.AP = apDict
+		page.AddAnnotation(widget.PdfAnnotation)
+	}
+
+	e, err := NewWithOptions(page, &ExtractOptions{IncludeWidgetAppearances: true})
+	if err != nil {
+		t.Fatalf("NewWithOptions failed. err=%v", err)
+	}
+	pt, _, _, err := e.ExtractPageText()
+	if err != nil {
+		t.Fatalf("ExtractPageText failed. err=%v", err)
+	}
+	expected := map[string][]float64{"Label": {700, 500}, "Alice": {700}, "Bob": {600}}
+	got := map[string][]float64{}
+	for _, tl := range pt.Lines() {
+		for _, word := range lineWords(tl.Marks.Elements()) {
+			bbox, _ := marksBBox(word)
+			got[marksText(word)] = append(got[marksText(word)], bbox.Lly)
+		}
+	}
+	if len(got) != len(expected) {
+		t.Fatalf("words=%v expected %v. text=%q", got, expected, pt.Text())
+	}
+	for text, ys := range expected {
+		if fmt.Sprint(got[text]) != fmt.Sprint(ys) {
+			t.Fatalf("%q at y=%v expected %v. text=%q", text, got[text], ys, pt.Text())
+		}
+	}
+}
+
+// TestMarkGaps checks that TextMark.Gap is the distance to the next text on the line.
+func TestMarkGaps(t *testing.T) {
+	// Courier glyphs are 6 points wide at 10 points so "conf" is spaced out by its 5 point
+	// character spacing.
+	contents := `
+        BT
+        /UniDocCourier 10 Tf
+        10 700 Td 5 Tc (conf) Tj
+        0 -20 Td 0 Tc (six) Tj
+        ET`
+	pt := fragmentPageText(t, contents)
+	if text := pt.Text(); text != "c o n f\nsix" {
+		t.Fatalf("text=%q", text)
+	}
+	expected := map[string]float64{"c": 5, "o": 5, "n": 5, "f": 0, "s": 0, "i": 0, "x": 0}
+	for _, tm := range pt.Marks().Elements() {
+		gap, ok := expected[tm.Text]
+		if tm.Meta {
+			gap, ok = 0, true
+		}
+		if ok && math.Abs(tm.Gap-gap) > 0.01 {
+			t.Fatalf("%s: Gap=%g expected %g", tm, tm.Gap, gap)
+		}
+	}
+}
+
+// TestTextOutsideTextObject checks that text shown outside BT/ET is extracted at the position
+// given by the current transformation matrix.
+func TestTextOutsideTextObject(t *testing.T) {
+	contents := `
+        /UniDocCourier 10 Tf
+        1 0 0 1 100 500 cm
+        (Hello) Tj
+        BT 10 700 Td (World) Tj ET
+        1 0 0 1 0 -100 cm
+        (Again) Tj`
+	pt := fragmentPageText(t, contents)
+	text := pt.Text()
+	for _, test := range []struct {
+		word     string
+		llx, lly float64
+	}{
+		{"Hello", 100, 500},
+		{"World", 110, 1200},
+		{"Again", 100, 400},
+	} {
+		start := strings.Index(text, test.word)
+		if start < 0 {
+			t.Fatalf("%q not extracted. text=%q", test.word, text)
+		}
+		bbox, ok := pt.BBoxForRange(start, start+len(test.word))
+		if !ok || math.Abs(bbox.Llx-test.llx) > 0.01 || math.Abs(bbox.Lly-test.lly) > 3 {
+			t.Fatalf("%q: bbox=%+v expected (%g, %g)", test.word, bbox, test.llx, test.lly)
+		}
+	}
+}
+
+// TestRenderMode checks that TextMark.RenderMode is set from the Tr operator.
+func TestRenderMode(t *testing.T) {
+	contents := `
+        BT
+        /UniDocCourier 10 Tf
+        10 700 Td (A) Tj
+        1 Tr 0 -20 Td (B) Tj
+        2 Tr 0 -20 Td (C) Tj
+        3 Tr 0 -20 Td (D) Tj
+        7 Tr 0 -20 Td (E) Tj
+        4 Tr 0 -20 Td (F) Tj
+        5 Tr 0 -20 Td (G) Tj
+        6 Tr 0 -20 Td (H) Tj
+        ET`
+	expected := map[string]RenderMode{
+		"A": RenderModeFill,
+		"B": RenderModeStroke,
+		"C": RenderModeFill | RenderModeStroke,
+		"D": 0,
+		"E": RenderModeClip,
+		"F": RenderModeFill | RenderModeClip,
+		"G": RenderModeStroke | RenderModeClip,
+		"H": RenderModeFill | RenderModeStroke | RenderModeClip,
+	}
+	pt := fragmentPageText(t, contents)
+	n := 0
+	for _, tm := range pt.Marks().Elements() {
+		if tm.Meta {
+			continue
+		}
+		n++
+		if mode, ok := expected[tm.Text]; !ok || tm.RenderMode != mode {
+			t.Fatalf("%q: RenderMode=%d expected %d", tm.Text, tm.RenderMode, mode)
+		}
+	}
+	if n != len(expected) {
+		t.Fatalf("%d marks expected %d", n, len(expected))
+	}
+}
+
+// TestExcludeInvisibleText checks that ExtractOptions.ExcludeInvisibleText skips the text of
+// render modes Tr 3 and Tr 7, which is neither filled nor stroked, and keeps the text of the other
+// modes, including the clipping modes Tr 4, 5 and 6.
+func TestExcludeInvisibleText(t *testing.T) {
+	contents := `
+        BT
+        /UniDocCourier 10 Tf
+        10 700 Td
+        0 Tr (0) Tj 1 Tr (1) Tj 2 Tr (2) Tj 3 Tr (3) Tj
+        4 Tr (4) Tj 5 Tr (5) Tj 6 Tr (6) Tj 7 Tr (7) Tj
+        0 Tr (8) Tj
+        ET`
+	for _, exclude := range []bool{false, true} {
+		e := NewFromContents(contents, fragmentResources(), fragmentMediaBox)
+		e.options.ExcludeInvisibleText = exclude
+		pt, _, _, err := e.ExtractPageText()
+		if err != nil {
+			t.Fatalf("ExtractPageText failed. err=%v", err)
+		}
+		expected := "012345678"
+		if exclude {
+			expected = "0124568"
+		}
+		if text := strings.Replace(pt.Text(), " ", "", -1); text != expected {
+			t.Fatalf("ExcludeInvisibleText=%t: text=%q expected %q", exclude, pt.Text(), expected)
+		}
+		for _, tm := range pt.Marks().Elements() {
+			if !tm.Meta && tm.RenderMode.Invisible() != (tm.Text == "3" || tm.Text == "7") {
+				t.Fatalf("ExcludeInvisibleText=%t: %s Invisible=%t", exclude, tm,
+					tm.RenderMode.Invisible())
+			}
+		}
+	}
+}
+
+// TestSaveRestoreTextState checks that text state parameters changed between q and Q are restored
+// by Q, including a font that was first set between them.
+func TestSaveRestoreTextState(t *testing.T) {
+	contents := `
+        BT /UniDocCourier 10 Tf 10 700 Td (A) Tj ET
+        q
+        BT /UniDocHelvetica 12 Tf 1 Tr 5 Ts 10 650 Td (B) Tj ET
+        q Q Q Q
+        BT 10 600 Td (C) Tj ET`
+	expected := map[string]struct {
+		font string
+		size float64
+		mode RenderMode
+		lly  float64
+	}{
+		"A": {"Courier", 10, RenderModeFill, 700},
+		"B": {"Helvetica", 12, RenderModeStroke, 655},
+		"C": {"Courier", 10, RenderModeFill, 600},
+	}
+	pt := fragmentPageText(t, contents)
+	n := 0
+	for _, tm := range pt.Marks().Elements() {
+		if tm.Meta {
+			continue
+		}
+		n++
+		exp, ok := expected[tm.Text]
+		if !ok || tm.Font.BaseFont() != exp.font || tm.FontSize != exp.size ||
+			tm.RenderMode != exp.mode || math.Abs(tm.BBox.Lly-exp.lly) > 0.01 {
+			t.Fatalf("%s RenderMode=%d expected %+v", tm, tm.RenderMode, exp)
+		}
+	}
+	if n != len(expected) {
+		t.Fatalf("%d marks expected %d", n, len(expected))
+	}
+
+	// A font that is first set after q is unset by Q.
+	pt = fragmentPageText(t, `q BT /UniDocCourier 10 Tf 10 700 Td (A) Tj ET Q BT 10 650 Td (B) Tj ET`)
+	if n := pt.DefaultFontMarks(); n != 1 {
+		t.Fatalf("DefaultFontMarks=%d expected 1. text=%q", n, pt.Text())
+	}
+}
+
+// TestEffectiveFontSize checks that TextMark.EffectiveFontSize includes the scaling of the text
+// and current transformation matrices while FontSize is the Tf size.
+func TestEffectiveFontSize(t *testing.T) {
+	contents := `
+        BT /UniDocCourier 1 Tf 10 0 0 10 10 700 Tm (A) Tj ET
+        q 2 0 0 2 0 0 cm BT /UniDocCourier 6 Tf 10 300 Td (B) Tj ET Q`
+	expected := map[string][2]float64{
+		"A": {1, 10},
+		"B": {6, 12},
+	}
+	pt := fragmentPageText(t, contents)
+	for _, tm := range pt.Marks().Elements() {
+		if tm.Meta {
+			continue
+		}
+		exp := expected[tm.Text]
+		if tm.FontSize != exp[0] || math.Abs(tm.EffectiveFontSize-exp[1]) > 0.01 {
+			t.Fatalf("%s: FontSize=%g EffectiveFontSize=%g expected %v", tm, tm.FontSize,
+				tm.EffectiveFontSize, exp)
+		}
+	}
+}
+
+// TestHorizontalScaling checks that the widths and positions of the bounding boxes of marks drawn
+// with Tz horizontal scaling are scaled.
+func TestHorizontalScaling(t *testing.T) {
+	for _, test := range []struct {
+		tz    float64
+		width float64 // Width of a Courier glyph at 10 points with `tz` scaling.
+	}{
+		{100, 6},
+		{200, 12},
+		{50, 3},
+	} {
+		contents := fmt.Sprintf(`BT /UniDocCourier 10 Tf %g Tz 10 700 Td (AB) Tj ET`, test.tz)
+		pt := fragmentPageText(t, contents)
+		marks := pt.Marks().Elements()
+		if len(marks) != 2 {
+			t.Fatalf("Tz=%g: %d marks expected 2. marks=%v", test.tz, len(marks), marks)
+		}
+		for i, tm := range marks {
+			llx := 10 + float64(i)*test.width
+			if math.Abs(tm.BBox.Llx-llx) > 0.01 || math.Abs(tm.BBox.Width()-test.width) > 0.01 {
+				t.Fatalf("Tz=%g: %s expected Llx=%g width=%g", test.tz, tm, llx, test.width)
+			}
+		}
+	}
+}
+
+// TestSingularTextMatrix checks that text drawn with a singular text rendering matrix, which
+// collapses it onto a line or a point, is skipped.
+func TestSingularTextMatrix(t *testing.T) {
+	contents := `
+        BT
+        /UniDocCourier 10 Tf
+        10 700 Td (Hello) Tj
+        1 0 0 0 10 680 Tm (Line) Tj
+        0 0 0 0 10 670 Tm (Point) Tj
+        1 0 0 1 10 660 Tm 0 Tz (Squashed) Tj 100 Tz
+        1 0 0 1 10 650 Tm /UniDocCourier 0 Tf (Tiny) Tj /UniDocCourier 10 Tf
+        1 0 0 1 10 640 Tm (World) Tj
+        ET`
+	pt := fragmentPageText(t, contents)
+	if text := pt.Text(); text != "Hello\nWorld" {
+		t.Fatalf("text=%q expected %q", text, "Hello\nWorld")
+	}
+	for _, tm := range pt.Marks().Elements() {
+		if !tm.Meta && tm.BBox.Urx <= tm.BBox.Llx {
+			t.Fatalf("degenerate mark %s", tm)
+		}
+	}
+}
+
+// TestMalformedShowText checks that text showing operators with operands of the wrong type are
+// skipped rather than stopping extraction.
+func TestMalformedShowText(t *testing.T) {
+	contents := `
+        BT
+        /UniDocCourier 10 Tf
+        12 TL 10 700 Td 42 Tj (Hello) Tj
+        /Name ' (World) '
+        1 2 3 " T* [(A) /B (C)] TJ
+        ET`
+	expected := "Hello\nWorld\nAC"
+	pt := fragmentPageText(t, contents)
+	if text := pt.Text(); text != expected {
+		t.Fatalf("text=%q expected=%q", text, expected)
+	}
+}
+
+// TestTextKnockout checks that overlapping copies of characters are removed unless they are drawn
+// with text knockout off.
+func TestTextKnockout(t *testing.T) {
+	gsDict := core.MakeDict()
+	gsDict.Set("TK", core.MakeBool(false))
+	resources := fragmentResources()
+	if err := resources.AddExtGState("GS0", gsDict); err != nil {
+		t.Fatalf("AddExtGState failed. err=%v", err)
+	}
+	for _, test := range []struct {
+		gs       string
+		expected string
+	}{
+		{"", "Shadow"},
+		{"/GS0 gs", "SShhaaddooww"},
+	} {
+		contents := test.gs + `
+        BT
+        /UniDocCourier 10 Tf
+        1 0 0 1 101 699 Tm (Shadow) Tj
+        1 0 0 1 100 700 Tm (Shadow) Tj
+        ET`
+		e := Extractor{resources: resources, contents: contents}
+		text, err := e.ExtractText()
+		if err != nil {
+			t.Fatalf("ExtractText failed. err=%v", err)
+		}
+		if text != test.expected {
+			t.Fatalf("gs=%q: text=%q expected %q", test.gs, text, test.expected)
+		}
+	}
+}
+
+// TestOverprintTolerance checks that ExtractOptions.OverprintTolerance controls the removal of
+// glyphs that are drawn several times with small offsets.
+func TestOverprintTolerance(t *testing.T) {
+	for _, test := range []struct {
+		dx        float64 // Offset of the copies of the text.
+		tolerance float64
+		expected  string
+	}{
+		{0.3, 0, "Bold"},
+		{0.3, -1, "BBBooolllddd"},
+		{2.4, 0, "BBBooolllddd"},
+		{2.4, 0.5, "Bold"},
+	} {
+		contents := fmt.Sprintf(`
+        BT
+        /UniDocCourier 10 Tf
+        1 0 0 1 100 700 Tm (Bold) Tj
+        1 0 0 1 %g 700 Tm (Bold) Tj
+        1 0 0 1 %g 700 Tm (Bold) Tj
+        ET`, 100+test.dx, 100+2*test.dx)
+		e := NewFromContents(contents, fragmentResources(), fragmentMediaBox)
+		e.options.OverprintTolerance = test.tolerance
+		pt, _, _, err := e.ExtractPageText()
+		if err != nil {
+			t.Fatalf("ExtractPageText failed. err=%v", err)
+		}
+		if text := pt.Text(); text != test.expected {
+			t.Fatalf("dx=%g tolerance=%g: text=%q expected %q", test.dx, test.tolerance, text,
+				test.expected)
+		}
+	}
+}
+
+// TestSplitLigatures checks that ExtractOptions.SplitLigatures splits glyphs that map to several
+// runes into one mark per rune.
+func TestSplitLigatures(t *testing.T) {
+	cmap := `/CIDInit /ProcSet findresource begin
+12 dict begin
+begincmap
+/CMapName /Ligature def
+/CMapType 2 def
+1 begincodespacerange
+<00> <FF>
+endcodespacerange
+3 beginbfchar
+<41> <00660069>
+<42> <0062>
+<43> <0063>
+endbfchar
+endcmap
+CMapName currentdict /CMap defineresource pop
+end
+end`
+	toUnicode, err := core.MakeStream([]byte(cmap), core.NewRawEncoder())
+	if err != nil {
+		t.Fatalf("MakeStream failed. err=%v", err)
+	}
+	fontDict := core.MakeDict()
+	fontDict.Set("Type", core.MakeName("Font"))
+	fontDict.Set("Subtype", core.MakeName("TrueType"))
+	fontDict.Set("BaseFont", core.MakeName("Ligature"))
+	fontDict.Set("FirstChar", core.MakeInteger(65))
+	fontDict.Set("LastChar", core.MakeInteger(67))
+	fontDict.Set("Widths", core.MakeArrayFromIntegers([]int{600, 600, 600}))
+	fontDict.Set("ToUnicode", toUnicode)
+	resources := fragmentResources()
+	resources.SetFontByName("Ligature", fontDict)
+	contents := `BT /Ligature 10 Tf 10 700 Td (BAC) Tj ET`
+
+	for _, split := range []bool{false, true} {
+		e := Extractor{resources: resources, contents: contents,
+			options: ExtractOptions{SplitLigatures: split}}
+		pt, _, _, err := e.ExtractPageText()
+		if err != nil {
+			t.Fatalf("ExtractPageText failed. err=%v", err)
+		}
+		if text := pt.Text(); text != "bfic" {
+			t.Fatalf("SplitLigatures=%t: text=%q expected %q", split, text, "bfic")
+		}
+		var texts []string
+		marks := pt.Marks().Elements()
+		for _, tm := range marks {
+			texts = append(texts, tm.Text)
+		}
+		expected := []string{"b", "fi", "c"}
+		if split {
+			expected = []string{"b", "f", "i", "c"}
+		}
+		if strings.Join(texts, "|") != strings.Join(expected, "|") {
+			t.Fatalf("SplitLigatures=%t: marks=%q expected %q", split, texts, expected)
+		}
+		if split {
+			f, i := marks[1].BBox, marks[2].BBox
+			if math.Abs(f.Urx-i.Llx) > 1e-6 || math.Abs((f.Urx-f.Llx)-(i.Urx-i.Llx)) > 1e-6 {
+				t.Fatalf("ligature not split equally. f=%+v i=%+v", f, i)
+			}
+		}
+	}
+}
+
+// TestDetectTJTabs checks that ExtractOptions.DetectTJTabs inserts tabs at large TJ jumps.
+func TestDetectTJTabs(t *testing.T) {
+	contents := `BT /UniDocCourier 10 Tf 10 700 Td [(Item) -3000 (12.50) -250 (each)] TJ ET`
+	for _, test := range []struct {
+		detect   bool
+		expected string
+	}{
+		{false, "Item 12.50 each"},
+		{true, "Item\t12.50 each"},
+	} {
+		e := Extractor{resources: fragmentResources(), contents: contents,
+			options: ExtractOptions{DetectTJTabs: test.detect}}
+		text, err := e.ExtractText()
+		if err != nil {
+			t.Fatalf("ExtractText failed. err=%v", err)
+		}
+		if text != test.expected {
+			t.Fatalf("DetectTJTabs=%t: text=%q expected %q", test.detect, text, test.expected)
+		}
+	}
+}
+
+// TestLiteralTabs checks that tab characters shown in the text are extracted as tabs, separate
+// words and are not counted as undecoded characters.
+func TestLiteralTabs(t *testing.T) {
+	contents := `BT /UniDocCourier 10 Tf 10 700 Td (Name\011Value) Tj ET`
+	e := NewFromContents(contents, fragmentResources(), fragmentMediaBox)
+	pt, numChars, numMisses, err := e.ExtractPageText()
+	if err != nil {
+		t.Fatalf("ExtractPageText failed. err=%v", err)
+	}
+	if text := pt.Text(); text != "Name\tValue" {
+		t.Fatalf("text=%q expected %q", text, "Name\tValue")
+	}
+	if numChars != 10 || numMisses != 0 {
+		t.Fatalf("numChars=%d numMisses=%d expected 10 0", numChars, numMisses)
+	}
+	marks := pt.Marks().Elements()
+	if tab := marks[4]; tab.Text != "\t" || tab.Meta {
+		t.Fatalf("tab mark=%s expected a literal tab", tab)
+	}
+	words := lineWords(marks)
+	if len(words) != 2 || marksText(words[0]) != "Name" || marksText(words[1]) != "Value" {
+		t.Fatalf("words=%v expected Name, Value", words)
+	}
+}
+
+// TestRotation checks that PageText.Rotation() returns the page's normalized /Rotate value,
+// including values inherited from the page tree.
+func TestRotation(t *testing.T) {
+	parent := core.MakeDict()
+	parent.Set("Rotate", core.MakeInteger(-90))
+	for _, test := range []struct {
+		rotate   *int64
+		parent   core.PdfObject
+		expected int
+	}{
+		{nil, nil, 0},
+		{int64Ptr(90), nil, 90},
+		{int64Ptr(450), nil, 90},
+		{nil, parent, 270},
+		{int64Ptr(180), parent, 180},
+	} {
+		page := model.NewPdfPage()
+		page.Rotate = test.rotate
+		page.Parent = test.parent
+		page.Resources = fragmentResources()
+		e, err := New(page)
+		if err != nil {
+			t.Fatalf("New failed. err=%v", err)
+		}
+		pt, _, _, err := e.ExtractPageText()
+		if err != nil {
+			t.Fatalf("ExtractPageText failed. err=%v", err)
+		}
+		if pt.Rotation() != test.expected {
+			t.Fatalf("Rotate=%v parent=%v: Rotation=%d expected %d", test.rotate, test.parent,
+				pt.Rotation(), test.expected)
+		}
+	}
+}
+
+// int64Ptr returns a pointer to `i`.
+func int64Ptr(i int64) *int64 {
+	return &i
+}
+
+// TestDecodeConfidence checks that TextMark.DecodeConfidence tells how each mark's text was
+// decoded.
+func TestDecodeConfidence(t *testing.T) {
+	cmap := `/CIDInit /ProcSet findresource begin
+12 dict begin
+begincmap
+/CMapName /Partial def
+/CMapType 2 def
+1 begincodespacerange
+<00> <FF>
+endcodespacerange
+1 beginbfchar
+<41> <0041>
+endbfchar
+endcmap
+CMapName currentdict /CMap defineresource pop
+end
+end`
+	toUnicode, err := core.MakeStream([]byte(cmap), core.NewRawEncoder())
+	if err != nil {
+		t.Fatalf("MakeStream failed. err=%v", err)
+	}
+	// A font with a ToUnicode CMap that only maps "A" and no encoding.
+	fontDict := core.MakeDict()
+	fontDict.Set("Type", core.MakeName("Font"))
+	fontDict.Set("Subtype", core.MakeName("TrueType"))
+	fontDict.Set("BaseFont", core.MakeName("Partial"))
+	fontDict.Set("FirstChar", core.MakeInteger(65))
+	fontDict.Set("LastChar", core.MakeInteger(66))
+	fontDict.Set("Widths", core.MakeArrayFromIntegers([]int{600, 600}))
+	fontDict.Set("ToUnicode", toUnicode)
+	resources := fragmentResources()
+	resources.SetFontByName("Partial", fontDict)
+	contents := `BT /Partial 10 Tf 10 700 Td <414201> Tj /UniDocCourier 10 Tf (C) Tj ET`
+
+	e := Extractor{resources: resources, contents: contents}
+	pt, _, _, err := e.ExtractPageText()
+	if err != nil {
+		t.Fatalf("ExtractPageText failed. err=%v", err)
+	}
+	expected := []DecodeConfidence{
+		DecodeConfidenceHigh,
+		DecodeConfidenceLow,
+		DecodeConfidenceNone,
+		DecodeConfidenceMedium,
+	}
+	var confidences []DecodeConfidence
+	for _, tm := range pt.Marks().Elements() {
+		if !tm.Meta {
+			confidences = append(confidences, tm.DecodeConfidence)
+		}
+	}
+	if !reflect.DeepEqual(confidences, expected) {
+		t.Fatalf("text=%q confidences=%v expected %v", pt.Text(), confidences, expected)
+	}
+}
+
+// TestWordSpacingMultiByte checks that word spacing (Tw) is applied to the single byte character
+// code 32 of a simple font but not to spaces in a font with 2 byte codes.
+func TestWordSpacingMultiByte(t *testing.T) {
+	cmap := `/CIDInit /ProcSet findresource begin
+12 dict begin
+begincmap
+/CMapName /TwoByte def
+/CMapType 2 def
+1 begincodespacerange
+<0000> <FFFF>
+endcodespacerange
+3 beginbfchar
+<0020> <0020>
+<0041> <0041>
+<0042> <0042>
+endbfchar
+endcmap
+CMapName currentdict /CMap defineresource pop
+end
+end`
+	toUnicode, err := core.MakeStream([]byte(cmap), core.NewRawEncoder())
+	if err != nil {
+		t.Fatalf("MakeStream failed. err=%v", err)
+	}
+	cidSystemInfo := core.MakeDict()
+	cidSystemInfo.Set("Registry", core.MakeString("Adobe"))
+	cidSystemInfo.Set("Ordering", core.MakeString("Identity"))
+	cidSystemInfo.Set("Supplement", core.MakeInteger(0))
+	descendant := core.MakeDict()
+	descendant.Set("Type", core.MakeName("Font"))
+	descendant.Set("Subtype", core.MakeName("CIDFontType2"))
+	descendant.Set("BaseFont", core.MakeName("TwoByte"))
+	descendant.Set("CIDSystemInfo", cidSystemInfo)
+	descendant.Set("DW", core.MakeInteger(600))
+	fontDict := core.MakeDict()
+	fontDict.Set("Type", core.MakeName("Font"))
+	fontDict.Set("Subtype", core.MakeName("Type0"))
+	fontDict.Set("BaseFont", core.MakeName("TwoByte"))
+	fontDict.Set("Encoding", core.MakeName("Identity-H"))
+	fontDict.Set("DescendantFonts", core.MakeArray(descendant))
+	fontDict.Set("ToUnicode", toUnicode)
+	resources := fragmentResources()
+	resources.SetFontByName("TwoByte", fontDict)
+
+	// "B" starts after 2 glyphs 6 points wide, plus the word spacing for the simple font.
+	for _, test := range []struct {
+		contents string
+		bx       float64
+	}{
+		{`BT /UniDocCourier 10 Tf 20 Tw 10 700 Td (A B) Tj ET`, 42},
+		{`BT /TwoByte 10 Tf 20 Tw 10 700 Td <004100200042> Tj ET`, 22},
+	} {
+		e := NewFromContents(test.contents, resources, fragmentMediaBox)
+		pt, _, _, err := e.ExtractPageText()
+		if err != nil {
+			t.Fatalf("ExtractPageText failed. contents=%q err=%v", test.contents, err)
+		}
+		var bx float64
+		for _, tm := range pt.Marks().Elements() {
+			if tm.Text == "B" {
+				bx = tm.BBox.Llx
+			}
+		}
+		if math.Abs(bx-test.bx) > 0.01 {
+			t.Fatalf("contents=%q: B at x=%.2f expected %.2f. text=%q", test.contents, bx, test.bx,
+				pt.Text())
+		}
+	}
+}
+
+// TestKeepControlChars checks that NUL glyphs are only extracted if
+// ExtractOptions.KeepControlChars is set and that control characters are flagged.
+func TestKeepControlChars(t *testing.T) {
+	cmap := `/CIDInit /ProcSet findresource begin
+12 dict begin
+begincmap
+/CMapName /Control def
+/CMapType 2 def
+1 begincodespacerange
+<00> <FF>
+endcodespacerange
+4 beginbfchar
+<01> <0000>
+<02> <0007>
+<41> <0041>
+<42> <0042>
+endbfchar
+endcmap
+CMapName currentdict /CMap defineresource pop
+end
+end`
+	toUnicode, err := core.MakeStream([]byte(cmap), core.NewRawEncoder())
+	if err != nil {
+		t.Fatalf("MakeStream failed. err=%v", err)
+	}
+	widths := make([]int, 66)
+	for i := range widths {
+		widths[i] = 600
+	}
+	fontDict := core.MakeDict()
+	fontDict.Set("Type", core.MakeName("Font"))
+	fontDict.Set("Subtype", core.MakeName("TrueType"))
+	fontDict.Set("BaseFont", core.MakeName("Control"))
+	fontDict.Set("FirstChar", core.MakeInteger(1))
+	fontDict.Set("LastChar", core.MakeInteger(66))
+	fontDict.Set("Widths", core.MakeArrayFromIntegers(widths))
+	fontDict.Set("ToUnicode", toUnicode)
+	resources := fragmentResources()
+	resources.SetFontByName("Control", fontDict)
+	contents := `BT /Control 10 Tf 10 700 Td <41010242> Tj ET`
+
+	for _, keep := range []bool{false, true} {
+		e := Extractor{resources: resources, contents: contents,
+			options: ExtractOptions{KeepControlChars: keep}}
+		pt, _, _, err := e.ExtractPageText()
+		if err != nil {
+			t.Fatalf("ExtractPageText failed. err=%v", err)
+		}
+		expected, bx := "A\aB", 22.0
+		if keep {
+			expected, bx = "A\x00\aB", 28.0
+		}
+		if text := pt.Text(); text != expected {
+			t.Fatalf("KeepControlChars=%t: text=%q expected %q", keep, text, expected)
+		}
+		for _, tm := range pt.Marks().Elements() {
+			control := tm.Text == "\x00" || tm.Text == "\a"
+			if tm.Control != control {
+				t.Fatalf("KeepControlChars=%t: %s Control=%t", keep, tm, tm.Control)
+			}
+			if tm.Text == "B" && math.Abs(tm.BBox.Llx-bx) > 0.01 {
+				t.Fatalf("KeepControlChars=%t: B at x=%.2f expected %.2f", keep, tm.BBox.Llx, bx)
+			}
+		}
+	}
+}
+
+// TestDefaultFontMarks checks that text shown before a font is set is counted but, as it has no
+// font size, is not added to the marks.
+func TestDefaultFontMarks(t *testing.T) {
+	contents := `BT 10 TL 10 700 Td (Hi) Tj /UniDocCourier 10 Tf (there) ' ET`
+	pt := fragmentPageText(t, contents)
+	if n := pt.DefaultFontMarks(); n != 2 {
+		t.Fatalf("DefaultFontMarks=%d expected 2. text=%q", n, pt.Text())
+	}
+	if text := pt.Text(); text != "there" {
+		t.Fatalf("text=%q expected %q", text, "there")
+	}
+}
+
+// TestActualText checks that the text drawn in a marked content sequence with an /ActualText
+// entry is replaced by the replacement text as a single mark.
+func TestActualText(t *testing.T) {
+	contents := `BT /UniDocCourier 10 Tf 10 700 Td (The ) Tj
+        /Span << /ActualText (first) >> BDC (f i r s t) Tj EMC
+        ( line) Tj /Span << /ActualText () >> BDC (*) Tj EMC ET`
+	pt := fragmentPageText(t, contents)
+	if text := pt.Text(); text != "The first line" {
+		t.Fatalf("text=%q expected %q", text, "The first line")
+	}
+	start := strings.Index(pt.Text(), "first")
+	marks, err := pt.Marks().RangeOffset(start, start+len("first"))
+	if err != nil {
+		t.Fatalf("RangeOffset failed. err=%v", err)
+	}
+	if marks.Len() != 1 {
+		t.Fatalf("%d marks expected 1. marks=%s", marks.Len(), marks)
+	}
+	// "f i r s t" is drawn from x=34 to x=88.
+	tm := marks.Elements()[0]
+	if tm.Original != "f i r s t" || math.Abs(tm.BBox.Llx-34) > 0.01 || math.Abs(tm.BBox.Urx-88) > 0.01 {
+		t.Fatalf("incorrect mark %s original=%q", tm, tm.Original)
+	}
+}
+
+// TestMarkedContentLang checks that TextMark.Lang is set from the /Lang entry of the innermost
+// marked content sequence that has one.
+func TestMarkedContentLang(t *testing.T) {
+	contents := `
+        /Span << /Lang (de-DE) >> BDC BT /UniDocCourier 10 Tf 10 700 Td (Hallo) Tj ET EMC
+        BT /UniDocCourier 10 Tf 10 680 Td (Hello) Tj ET
+        /P << /Lang (fr) >> BDC /Span << /MCID 3 >> BDC
+        BT /UniDocCourier 10 Tf 10 660 Td (Bonjour) Tj ET
+        EMC EMC`
+	expected := map[string]string{"Hallo": "de-DE", "Hello": "", "Bonjour": "fr"}
+	pt := fragmentPageText(t, contents)
+	for _, tl := range pt.Lines() {
+		for _, tm := range tl.Marks.Elements() {
+			if lang := expected[tl.Text]; tm.Lang != lang {
+				t.Fatalf("%q: %s Lang=%q expected %q", tl.Text, tm, tm.Lang, lang)
+			}
+		}
+	}
+}
+
+// TestWordAcrossForm checks that a word that is partly drawn in a form XObject and partly in the
+// page contents is extracted as a single word, whichever is drawn first. The form's matrix
+// positions its part of the word.
+func TestWordAcrossForm(t *testing.T) {
+	resources := fragmentResources()
+	xform := model.NewXObjectForm()
+	xform.BBox = core.MakeArrayFromFloats([]float64{0, 0, 612, 792})
+	xform.Matrix = core.MakeArrayFromFloats([]float64{1, 0, 0, 1, 18, 0})
+	err := xform.SetContentStream([]byte(`BT /UniDocCourier 10 Tf 10 700 Td (lo) Tj ET`),
+		core.NewRawEncoder())
+	if err != nil {
+		t.Fatalf("SetContentStream failed. err=%v", err)
+	}
+	if err := resources.SetXObjectFormByName("Fm1", xform); err != nil {
+		t.Fatalf("SetXObjectFormByName failed. err=%v", err)
+	}
+	for _, contents := range []string{
+		`BT /UniDocCourier 10 Tf 10 700 Td (Hel) Tj ET /Fm1 Do`,
+		`/Fm1 Do BT /UniDocCourier 10 Tf 10 700 Td (Hel) Tj ET`,
+	} {
+		e := NewFromContents(contents, resources, fragmentMediaBox)
+		pt, _, _, err := e.ExtractPageText()
+		if err != nil {
+			t.Fatalf("ExtractPageText failed. err=%v", err)
+		}
+		if text := pt.Text(); text != "Hello" {
+			t.Fatalf("contents=%q: text=%q expected %q", contents, text, "Hello")
+		}
+	}
+}
+
+// TestSameFontNameInForm checks that a font resource name that refers to different fonts in a page
+// and in a form XObject drawn on it is decoded with the right font in each, before and after the
+// form, even though the fonts are cached.
+func TestSameFontNameInForm(t *testing.T) {
+	courier := model.NewStandard14FontMustCompile(model.CourierName)
+	bold := model.NewStandard14FontMustCompile(model.HelveticaBoldName)
+	resources := model.NewPdfPageResources()
+	resources.SetFontByName("F1", courier.ToPdfObject())
+	formResources := model.NewPdfPageResources()
+	formResources.SetFontByName("F1", bold.ToPdfObject())
+
+	xform := model.NewXObjectForm()
+	xform.BBox = core.MakeArrayFromFloats([]float64{0, 0, 612, 792})
+	xform.Resources = formResources
+	err := xform.SetContentStream([]byte(`BT /F1 10 Tf 10 650 Td (Form) Tj ET`),
+		core.NewRawEncoder())
+	if err != nil {
+		t.Fatalf("SetContentStream failed. err=%v", err)
+	}
+	if err := resources.SetXObjectFormByName("Fm1", xform); err != nil {
+		t.Fatalf("SetXObjectFormByName failed. err=%v", err)
+	}
+	contents := `
+        BT /F1 10 Tf 10 700 Td (Before) Tj ET
+        /Fm1 Do
+        BT /F1 10 Tf 10 600 Td (After) Tj ET`
+	e := NewFromContents(contents, resources, fragmentMediaBox)
+	pt, _, _, err := e.ExtractPageText()
+	if err != nil {
+		t.Fatalf("ExtractPageText failed. err=%v", err)
+	}
+	if text := pt.Text(); text != "Before\nForm\nAfter" {
+		t.Fatalf("text=%q expected %q", text, "Before\nForm\nAfter")
+	}
+	expected := map[string]string{
+		"Before": "Courier",
+		"Form":   "Helvetica-Bold",
+		"After":  "Courier",
+	}
+	for _, line := range pt.Lines() {
+		for _, tm := range line.Marks.Elements() {
+			if baseFont := tm.Font.BaseFont(); baseFont != expected[line.Text] {
+				t.Fatalf("line %q: mark %s has font %q expected %q", line.Text, tm, baseFont,
+					expected[line.Text])
+			}
+		}
+	}
+}
+
+// TestCyclicForms checks that forms that draw themselves, directly or through other forms, are
+// drawn once rather than recursing forever, and that the rest of the page is extracted.
+func TestCyclicForms(t *testing.T) {
+	fonts := core.MakeDict()
+	fonts.Set("F1", model.NewStandard14FontMustCompile(model.CourierName).ToPdfObject())
+	// newForm returns a form XObject with content stream `contents` and the XObjects in
+	// `xobjects`, which may be filled in later.
+	newForm := func(contents string, xobjects *core.PdfObjectDictionary) *core.PdfObjectStream {
+		stream, err := core.MakeStream([]byte(contents), core.NewRawEncoder())
+		if err != nil {
+			t.Fatalf("MakeStream failed. err=%v", err)
+		}
+		resources := core.MakeDict()
+		resources.Set("Font", fonts)
+		resources.Set("XObject", xobjects)
+		stream.Set("Type", core.MakeName("XObject"))
+		stream.Set("Subtype", core.MakeName("Form"))
+		stream.Set("BBox", core.MakeArrayFromFloats([]float64{0, 0, 612, 792}))
+		stream.Set("Resources", resources)
+		return stream
+	}
+	xobjectsA, xobjectsB, xobjectsC := core.MakeDict(), core.MakeDict(), core.MakeDict()
+	formA := newForm(`BT /F1 10 Tf 10 700 Td (A) Tj ET /FmB Do`, xobjectsA)
+	formB := newForm(`BT /F1 10 Tf 10 680 Td (B) Tj ET /FmA Do`, xobjectsB)
+	formC := newForm(`BT /F1 10 Tf 10 660 Td (C) Tj ET /FmC Do`, xobjectsC)
+	xobjectsA.Set("FmB", formB)
+	xobjectsB.Set("FmA", formA)
+	xobjectsC.Set("FmC", formC)
+
+	resources := model.NewPdfPageResources()
+	resources.SetFontByName("F1", fonts.Get("F1"))
+	for name, form := range map[string]*core.PdfObjectStream{"FmA": formA, "FmC": formC} {
+		if err := resources.SetXObjectByName(core.PdfObjectName(name), form); err != nil {
+			t.Fatalf("SetXObjectByName failed. err=%v", err)
+		}
+	}
+	contents := `/FmA Do /FmC Do BT /F1 10 Tf 10 640 Td (Page) Tj ET`
+	e := NewFromContents(contents, resources, fragmentMediaBox)
+	pt, _, _, err := e.ExtractPageText()
+	if err != nil {
+		t.Fatalf("ExtractPageText failed. err=%v", err)
+	}
+	expected := "A\nB\nC\nPage"
+	if text := pt.Text(); text != expected {
+		t.Fatalf("text=%q expected %q", text, expected)
+	}
+}
+
+// TestInterleavedColumns checks that words are assembled correctly when the parts of words in
+// different columns are drawn alternately.
+func TestInterleavedColumns(t *testing.T) {
+	contents := `
+        BT
+        /UniDocCourier 10 Tf
+        1 0 0 1 10 700 Tm (Alp) Tj
+        1 0 0 1 100 700 Tm (Be) Tj
+        1 0 0 1 28 700 Tm (ha) Tj
+        1 0 0 1 112 700 Tm (ta) Tj
+        1 0 0 1 10 680 Tm (Gam) Tj
+        1 0 0 1 100 680 Tm (De) Tj
+        1 0 0 1 28 680 Tm (ma) Tj
+        1 0 0 1 112 680 Tm (lta) Tj
+        ET`
+	pt := fragmentPageText(t, contents)
+	expected := "Alpha Beta\nGamma Delta"
+	if text := pt.Text(); text != expected {
+		t.Fatalf("text=%q expected %q", text, expected)
+	}
+}
+
+// TestTextWithOrder checks that PageText.TextWithOrder() returns the page text in the order of a
+// caller's comparator.
+func TestTextWithOrder(t *testing.T) {
+	contents := `
+        BT
+        /UniDocCourier 10 Tf
+        1 0 0 1 10 700 Tm (Left one) Tj
+        1 0 0 1 10 680 Tm (Left two) Tj
+        1 0 0 1 300 700 Tm (Right one) Tj
+        1 0 0 1 300 680 Tm (Right two) Tj
+        ET`
+	pt := fragmentPageText(t, contents)
+	if text := pt.TextWithOrder(func(a, b TextMark) bool { return false }); text != pt.Text() {
+		t.Fatalf("reading order text=%q expected %q", text, pt.Text())
+	}
+	column := func(tm TextMark) int {
+		if tm.BBox.Llx < 200 {
+			return 0
+		}
+		return 1
+	}
+	text := pt.TextWithOrder(func(a, b TextMark) bool { return column(a) < column(b) })
+	expected := "Left one\nLeft two\nRight one\nRight two"
+	if text != expected {
+		t.Fatalf("text=%q expected %q", text, expected)
+	}
+}
+
+// TestRawText checks that PageText.RawText() returns the text in the order it was drawn, also
+// after the marks have been sorted into reading order and restricted to an area.
+func TestRawText(t *testing.T) {
+	contents := `
+        BT
+        /UniDocCourier 10 Tf
+        1 0 0 1 10 600 Tm (world) Tj
+        1 0 0 1 10 700 Tm (Hello) Tj
+        1 0 0 1 10 500 Tm (again) Tj
+        ET`
+	pt := fragmentPageText(t, contents)
+	if text := pt.Text(); text != "Hello\nworld\nagain" {
+		t.Fatalf("text=%q expected %q", text, "Hello\nworld\nagain")
+	}
+	if raw := pt.RawText(); raw != "worldHelloagain" {
+		t.Fatalf("RawText=%q expected %q", raw, "worldHelloagain")
+	}
+	pt.ApplyArea(model.PdfRectangle{Llx: 0, Lly: 550, Urx: 612, Ury: 792})
+	if raw := pt.RawText(); raw != "worldHello" {
+		t.Fatalf("RawText=%q expected %q after ApplyArea", raw, "worldHello")
+	}
+}
+
+// TestLineSeparator checks that ExtractOptions.LineSeparator is inserted between lines and that
+// the TextMark offsets are consistent with the extracted text.
+func TestLineSeparator(t *testing.T) {
+	contents := `
+        BT
+        /UniDocCourier 10 Tf
+        10 700 Td (First line) Tj
+        0 -20 Td (Second line) Tj
+        0 -20 Td (Third) Tj
+        ET`
+	for _, test := range []struct {
+		sep      string
+		expected string
+	}{
+		{"", "First line\nSecond line\nThird"},
+		{"\n\n", "First line\n\nSecond line\n\nThird"},
+		{" | ", "First line | Second line | Third"},
+	} {
+		e := Extractor{resources: fragmentResources(), contents: contents,
+			options: ExtractOptions{LineSeparator: test.sep}}
+		pt, _, _, err := e.ExtractPageText()
+		if err != nil {
+			t.Fatalf("ExtractPageText failed. err=%v", err)
+		}
+		text := pt.Text()
+		if text != test.expected {
+			t.Fatalf("LineSeparator=%q: text=%q expected=%q", test.sep, text, test.expected)
+		}
+		for _, tm := range pt.Marks().Elements() {
+			if text[tm.Offset:tm.Offset+len(tm.Text)] != tm.Text {
+				t.Fatalf("LineSeparator=%q: inconsistent mark %s", test.sep, tm)
+			}
+		}
+		if lines := pt.viewLines(); len(lines) != 3 {
+			t.Fatalf("LineSeparator=%q: %d lines expected 3", test.sep, len(lines))
+		}
+	}
+}
+
+// TestInferredSpace checks that ExtractOptions.InferredSpace replaces the spaces inferred from
+// gaps between marks but not the spaces drawn in the PDF.
+func TestInferredSpace(t *testing.T) {
+	contents := `BT /UniDocCourier 10 Tf 10 700 Td (x = 1) Tj 40 0 Td (y) Tj ET`
+	for _, test := range []struct {
+		space    string
+		expected string
+	}{
+		{"", "x = 1 y"},
+		{"\u2423", "x = 1\u2423y"},
+	} {
+		e := Extractor{resources: fragmentResources(), contents: contents,
+			options: ExtractOptions{InferredSpace: test.space}}
+		pt, _, _, err := e.ExtractPageText()
+		if err != nil {
+			t.Fatalf("ExtractPageText failed. err=%v", err)
+		}
+		text := pt.Text()
+		if text != test.expected {
+			t.Fatalf("InferredSpace=%q: text=%q expected=%q", test.space, text, test.expected)
+		}
+		for _, tm := range pt.Marks().Elements() {
+			if text[tm.Offset:tm.Offset+len(tm.Text)] != tm.Text {
+				t.Fatalf("InferredSpace=%q: inconsistent mark %s", test.space, tm)
+			}
+		}
+	}
+}
+
+// TestBBoxForRange checks the bounding boxes of substrings of the extracted text.
+func TestBBoxForRange(t *testing.T) {
+	contents := `
+        BT
+        /UniDocCourier 10 Tf
+        10 700 Td (First line) Tj
+        0 -20 Td (Second line) Tj
+        ET`
+	pt := fragmentPageText(t, contents)
+	text := pt.Text()
+	start := strings.Index(text, "line")
+	end := strings.Index(text, "Second") + len("Second")
+
+	// Courier glyphs are 0.6 em wide.
+	bboxes := pt.BBoxesForRange(start, end)
+	if len(bboxes) != 2 {
+		t.Fatalf("%d bboxes expected 2. bboxes=%v", len(bboxes), bboxes)
+	}
+	b0, b1 := bboxes[0], bboxes[1]
+	if math.Abs(b0.Llx-46) > 0.01 || math.Abs(b0.Urx-70) > 0.01 ||
+		math.Abs(b1.Llx-10) > 0.01 || math.Abs(b1.Urx-46) > 0.01 || b0.Lly <= b1.Ury {
+		t.Fatalf("incorrect bboxes=%v", bboxes)
+	}
+
+	bbox, ok := pt.BBoxForRange(start, end)
+	if !ok || bbox != rectUnion(b0, b1) {
+		t.Fatalf("incorrect bbox=%v ok=%t", bbox, ok)
+	}
+
+	if _, ok := pt.BBoxForRange(start, start); ok {
+		t.Fatalf("empty range has a bbox")
+	}
+}
+
+// TestRangeOffsetEnd checks that RangeOffset finds the TextMarks of ranges that end at or past
+// the last TextMark.
+func TestRangeOffsetEnd(t *testing.T) {
+	pt := fragmentPageText(t, `BT /UniDocCourier 10 Tf 10 700 Td (First line) Tj ET`)
+	text := pt.Text()
+	start := strings.Index(text, "line")
+	for _, end := range []int{len(text), len(text) + 10} {
+		spanMarks, err := pt.Marks().RangeOffset(start, end)
+		if err != nil {
+			t.Fatalf("RangeOffset(%d, %d) failed. err=%v", start, end, err)
+		}
+		var sb strings.Builder
+		for _, tm := range spanMarks.Elements() {
+			sb.WriteString(tm.Text)
+		}
+		if got := sb.String(); got != "line" {
+			t.Fatalf("RangeOffset(%d, %d) text=%q expected %q", start, end, got, "line")
+		}
+	}
+}
+
+// TestBaselineGrid checks that PageText.BaselineGrid() clusters the baselines of text lines.
+func TestBaselineGrid(t *testing.T) {
+	contents := `
+        BT
+        /UniDocCourier 10 Tf
+        10 700 Td (Line 1) Tj
+        0 -14 Td (Line) Tj 0.5 0 Td (2) Tj
+        0 1 -1 0 300 300 Tm (Rotated) Tj
+        ET
+        BT
+        /UniDocHelvetica 10 Tf
+        100 672 Td (Line 3) Tj
+        ET
+        `
+	pt := fragmentPageText(t, contents)
+	grid := pt.BaselineGrid()
+	expected := []float64{700, 686, 672}
+	if len(grid) != len(expected) {
+		t.Fatalf("grid=%.2f expected=%.2f", grid, expected)
+	}
+	for i, y := range grid {
+		if math.Abs(y-expected[i]) > 0.01 {
+			t.Fatalf("grid=%.2f expected=%.2f", grid, expected)
+		}
+	}
+}
+
+// TestDominantTextColor checks that text fill colors are extracted and that the dominant text
+// color is the color that covers the largest area.
+func TestDominantTextColor(t *testing.T) {
+	contents := `
+        BT
+        /UniDocCourier 10 Tf
+        1 0 0 rg
+        10 700 Td (Red text) Tj
+        ET
+        0 0 1 rg
+        BT
+        /UniDocCourier 10 Tf
+        10 600 Td (A longer blue line) Tj
+        0 0 0 1 k
+        0 50 Td (Black) Tj
+        ET
+        `
+	pt := fragmentPageText(t, contents)
+	red := color.RGBA{R: 255, A: 255}
+	blue := color.RGBA{B: 255, A: 255}
+	black := color.RGBA{A: 255}
+	if c := pt.DominantTextColor(); c != blue {
+		t.Fatalf("DominantTextColor=%v expected %v", c, blue)
+	}
+	text := pt.Text()
+	for _, test := range []struct {
+		term  string
+		color color.RGBA
+	}{
+		{"Red text", red},
+		{"blue", blue},
+		{"Black", black},
+	} {
+		start := strings.Index(text, test.term)
+		marks, err := pt.Marks().RangeOffset(start, start+len(test.term))
+		if err != nil {
+			t.Fatalf("RangeOffset failed. term=%q err=%v", test.term, err)
+		}
+		if c := marks.DominantTextColor(); c != test.color {
+			t.Fatalf("term=%q DominantTextColor=%v expected %v", test.term, c, test.color)
+		}
+	}
+}
+
+// TestColorSplitWord checks that a word whose letters are drawn in different colors is extracted
+// as one word and that the marks keep the colors of their letters.
+func TestColorSplitWord(t *testing.T) {
+	contents := `
+        BT
+        /UniDocCourier 10 Tf
+        10 700 Td
+        1 0 0 rg (W) Tj
+        0 g (arning sign) Tj
+        ET
+        `
+	pt := fragmentPageText(t, contents)
+	expected := "Warning sign"
+	if text := pt.Text(); text != expected {
+		t.Fatalf("text=%q expected %q", text, expected)
+	}
+	lines := pt.Lines()
+	if len(lines) != 1 {
+		t.Fatalf("%d lines expected 1. lines=%v", len(lines), lines)
+	}
+	words := lineWords(lines[0].Marks.Elements())
+	if len(words) != 2 || marksText(words[0]) != "Warning" {
+		t.Fatalf("words=%v expected Warning, sign", words)
+	}
+	red := color.RGBA{R: 255, A: 255}
+	black := color.RGBA{A: 255}
+	for i, tm := range words[0] {
+		expected := black
+		if i == 0 {
+			expected = red
+		}
+		if c := color.RGBAModel.Convert(tm.FillColor).(color.RGBA); c != expected {
+			t.Fatalf("mark %d %q: FillColor=%v expected %v", i, tm.Text, c, expected)
+		}
+	}
+}
+
+// TestMirroredText checks that text drawn with mirroring text matrices is extracted in the order
+// it is read and has valid bounding boxes.
+func TestMirroredText(t *testing.T) {
+	tests := []struct {
+		name   string
+		matrix string
+	}{
+		{"normal", "1 0 0 1"},
+		{"horizontal mirror", "-1 0 0 1"},
+		{"vertical mirror", "1 0 0 -1"},
+		{"rotated mirror", "0 1 1 0"},
+	}
+	for _, test := range tests {
+		contents := fmt.Sprintf(`BT /UniDocCourier 24 Tf %s 300 300 Tm (Hello) Tj 0 -30 Td (World) Tj ET`,
+			test.matrix)
+		pt := fragmentPageText(t, contents)
+		if text := pt.Text(); text != "Hello\nWorld" {
+			t.Fatalf("%s: text=%q", test.name, text)
+		}
+		for _, tm := range pt.Marks().Elements() {
+			b := tm.BBox
+			if !tm.Meta && (b.Llx >= b.Urx || b.Lly >= b.Ury) {
+				t.Fatalf("%s: bad bbox. tm=%s", test.name, tm)
+			}
+		}
+	}
+}
+
 //  TestTextSort checks that PageText.sortPosition() gives expected results
 func TestTextSort(t *testing.T) {
 	// marks0 is in the expected sort order for tol=15
@@ -669,8 +2271,10 @@ func (c pageContents) testPageTextAndMarks(t *testing.T, l *markupList, desc str
 	if spanMarks, err := textMarks.RangeOffset(-1, 0); err == nil {
 		t.Fatalf("textMarks.RangeOffset(-1, 0) succeeded. %s\n\tspanMarks=%s", desc, spanMarks)
 	}
-	if spanMarks, err := textMarks.RangeOffset(0, 1e10); err == nil {
-		t.Fatalf("textMarks.RangeOffset(0, 1e10) succeeded. %s\n\tspanMarks=%s", desc, spanMarks)
+	// Ranges that end past the last TextMark are clipped to it.
+	if spanMarks, err := textMarks.RangeOffset(0, 1e10); err != nil || spanMarks.Len() != textMarks.Len() {
+		t.Fatalf("textMarks.RangeOffset(0, 1e10) failed. %s\n\tspanMarks=%s err=%v",
+			desc, spanMarks, err)
 	}
 	if spanMarks, err := textMarks.RangeOffset(1, 0); err == nil {
 		t.Fatalf("textMarks.RangeOffset(1, 0) succeeded. %s\n\tspanMarks=%s", desc, spanMarks)
@@ -1081,3 +2685,43 @@ func (l *markupList) saveOutputPdf() {
 		l.t.Fatalf("WriteFile failed. metaPath=%q err=%v", metaPath, err)
 	}
 }
+
+// BenchmarkManyLabels measures text extraction from a page with 200,000 tiny labels scattered
+// over it like the labels of a map or CAD drawing.
+func BenchmarkManyLabels(b *testing.B) {
+	benchmarkManyLabels(b, 0)
+}
+
+// BenchmarkManyLabelsTiled measures text extraction from the page of BenchmarkManyLabels laid out
+// in tiles of about 10,000 labels.
+func BenchmarkManyLabelsTiled(b *testing.B) {
+	benchmarkManyLabels(b, 10000)
+}
+
+// benchmarkManyLabels measures text extraction from a page with 200,000 tiny labels with
+// ExtractOptions.TileMarks set to `tileMarks`.
+func benchmarkManyLabels(b *testing.B, tileMarks int) {
+	const numLabels = 200000
+	var sb strings.Builder
+	sb.WriteString("BT /UniDocCourier 2 Tf\n")
+	for i := 0; i < numLabels; i++ {
+		x := float64((i*7919)%6000) / 10
+		y := float64((i*104729)%7900) / 10
+		fmt.Fprintf(&sb, "1 0 0 1 %.1f %.1f Tm (L%d) Tj\n", x, y, i%100)
+	}
+	sb.WriteString("ET")
+	contents := sb.String()
+	resources := fragmentResources()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		e := NewFromContents(contents, resources, fragmentMediaBox)
+		e.options.TileMarks = tileMarks
+		pt, _, _, err := e.ExtractPageText()
+		if err != nil {
+			b.Fatalf("ExtractPageText failed. err=%v", err)
+		}
+		if len(pt.Text()) == 0 {
+			b.Fatalf("no text extracted")
+		}
+	}
+}
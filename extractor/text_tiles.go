@@ -0,0 +1,69 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package extractor
+
+import (
+	"math"
+)
+
+// tileMarks returns `marks` split into a square grid of tiles that each hold about `maxMarks`
+// marks if the marks are evenly spread. Marks are assigned to the tiles that contain their
+// centers. The tiles are returned in rows from top to bottom and left to right in each row. Empty
+// tiles are not returned.
+func tileMarks(marks []textMark, maxMarks int) [][]textMark {
+	n := int(math.Ceil(math.Sqrt(float64(len(marks)) / float64(maxMarks))))
+	if n <= 1 {
+		return [][]textMark{marks}
+	}
+	center := func(tm textMark) (float64, float64) {
+		return (tm.bbox.Llx + tm.bbox.Urx) / 2, (tm.bbox.Lly + tm.bbox.Ury) / 2
+	}
+	x0, y0 := math.Inf(1), math.Inf(1)
+	x1, y1 := math.Inf(-1), math.Inf(-1)
+	for _, tm := range marks {
+		x, y := center(tm)
+		x0, x1 = math.Min(x0, x), math.Max(x1, x)
+		y0, y1 = math.Min(y0, y), math.Max(y1, y)
+	}
+	// cell returns the index of the cell of `v` when the range `lo` to `hi` is split into `n`.
+	cell := func(v, lo, hi float64) int {
+		if hi <= lo {
+			return 0
+		}
+		i := int(float64(n) * (v - lo) / (hi - lo))
+		if i >= n {
+			i = n - 1
+		}
+		return i
+	}
+	// The marks are large so they are counted first and then copied once into a single slice
+	// that the tiles share.
+	tileOf := make([]int, len(marks))
+	counts := make([]int, n*n)
+	for i, tm := range marks {
+		x, y := center(tm)
+		row := n - 1 - cell(y, y0, y1) // Row 0 is at the top of the page.
+		tileOf[i] = row*n + cell(x, x0, x1)
+		counts[tileOf[i]]++
+	}
+	starts := make([]int, n*n)
+	for k := 1; k < n*n; k++ {
+		starts[k] = starts[k-1] + counts[k-1]
+	}
+	sorted := make([]textMark, len(marks))
+	next := append([]int(nil), starts...)
+	for i, tm := range marks {
+		sorted[next[tileOf[i]]] = tm
+		next[tileOf[i]]++
+	}
+	var tiles [][]textMark
+	for k, start := range starts {
+		if counts[k] > 0 {
+			tiles = append(tiles, sorted[start:start+counts[k]:start+counts[k]])
+		}
+	}
+	return tiles
+}
@@ -0,0 +1,42 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package extractor
+
+import (
+	"testing"
+)
+
+// TestTileMarks checks that pages with more than ExtractOptions.TileMarks marks are laid out one
+// tile at a time and that other pages are not.
+func TestTileMarks(t *testing.T) {
+	contents := `
+        BT
+        /UniDocCourier 10 Tf
+        1 0 0 1 10 700 Tm (A) Tj
+        1 0 0 1 300 700 Tm (B) Tj
+        1 0 0 1 10 100 Tm (C) Tj
+        1 0 0 1 300 100 Tm (D) Tj
+        ET`
+	for _, test := range []struct {
+		tileMarks int
+		expected  string
+	}{
+		{0, "A B\nC D"},
+		{4, "A B\nC D"},
+		// 4 marks with 1 mark per tile gives a 2 x 2 grid of tiles, which splits the lines.
+		{1, "A\nB\nC\nD"},
+	} {
+		e := NewFromContents(contents, fragmentResources(), fragmentMediaBox)
+		e.options.TileMarks = test.tileMarks
+		pt, _, _, err := e.ExtractPageText()
+		if err != nil {
+			t.Fatalf("ExtractPageText failed. err=%v", err)
+		}
+		if text := pt.Text(); text != test.expected {
+			t.Fatalf("TileMarks=%d: text=%q expected %q", test.tileMarks, text, test.expected)
+		}
+	}
+}
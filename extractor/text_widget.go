@@ -0,0 +1,152 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package extractor
+
+import (
+	"math"
+
+	"github.com/unidoc/unipdf/v3/common"
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/internal/transform"
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+// widgetAppearance is the normal appearance stream of a widget annotation. This is where the
+// values of filled form fields are drawn.
+type widgetAppearance struct {
+	contents  string
+	resources *model.PdfPageResources
+	// matrix maps the appearance stream's form space to the page's default user space.
+	matrix transform.Matrix
+}
+
+const (
+	// Annotation flags (Table 165 of the PDF 32000 spec) for annotations that are not displayed.
+	annotFlagHidden = 1 << 1
+	annotFlagNoView = 1 << 5
+)
+
+// widgetAppearances returns the normal appearance streams of the visible widget annotations on
+// `page`. Annotations that can't be read are skipped.
+func widgetAppearances(page *model.PdfPage) []widgetAppearance {
+	annotations, err := page.GetAnnotations()
+	if err != nil {
+		common.Log.Debug("ERROR: GetAnnotations failed. err=%v", err)
+		return nil
+	}
+	var widgets []widgetAppearance
+	for _, annot := range annotations {
+		if _, ok := annot.GetContext().(*model.PdfAnnotationWidget); !ok {
+			continue
+		}
+		if flags, ok := core.GetIntVal(annot.F); ok && flags&(annotFlagHidden|annotFlagNoView) != 0 {
+			continue
+		}
+		w, err := newWidgetAppearance(annot, page.Resources)
+		if err != nil {
+			common.Log.Debug("ERROR: Skipping widget annotation. err=%v", err)
+			continue
+		}
+		if w != nil {
+			widgets = append(widgets, *w)
+		}
+	}
+	return widgets
+}
+
+// newWidgetAppearance returns the normal appearance stream of widget annotation `annot` or nil if
+// it doesn't have one. `resources` are used if the appearance stream has no resources.
+func newWidgetAppearance(annot *model.PdfAnnotation, resources *model.PdfPageResources) (
+	*widgetAppearance, error) {
+	stream := normalAppearance(annot)
+	if stream == nil {
+		return nil, nil
+	}
+	arr, ok := core.GetArray(annot.Rect)
+	if !ok {
+		return nil, core.ErrTypeError
+	}
+	rect, err := model.NewPdfRectangle(*arr)
+	if err != nil {
+		return nil, err
+	}
+	xform, err := model.NewXObjectFormFromStream(stream)
+	if err != nil {
+		return nil, err
+	}
+	contents, err := xform.GetContentStream()
+	if err != nil {
+		return nil, err
+	}
+	matrix, ok := appearanceMatrix(xform, *rect)
+	if !ok {
+		return nil, nil
+	}
+	if xform.Resources != nil {
+		resources = xform.Resources
+	}
+	return &widgetAppearance{contents: string(contents), resources: resources, matrix: matrix}, nil
+}
+
+// normalAppearance returns the stream of the normal (/N) appearance of `annot` in its current
+// appearance state, or nil if there isn't one.
+func normalAppearance(annot *model.PdfAnnotation) *core.PdfObjectStream {
+	ap, ok := core.GetDict(annot.AP)
+	if !ok {
+		return nil
+	}
+	n := ap.Get("N")
+	if stream, ok := core.GetStream(n); ok {
+		return stream
+	}
+	// Fields such as check boxes have a subdictionary of appearances for each state.
+	states, ok := core.GetDict(n)
+	if !ok {
+		return nil
+	}
+	state, ok := core.GetName(annot.AS)
+	if !ok {
+		return nil
+	}
+	stream, _ := core.GetStream(states.Get(*state))
+	return stream
+}
+
+// appearanceMatrix returns the matrix that maps the form space of appearance stream `xform` to
+// the default user space of the page so that the appearance fits the annotation rectangle `rect`.
+// This is computed as described in section 12.5.5 of the PDF 32000 spec.
+// It returns false if the appearance stream's bounding box is degenerate.
+func appearanceMatrix(xform *model.XObjectForm, rect model.PdfRectangle) (transform.Matrix, bool) {
+	m := formMatrix(xform)
+	arr, ok := core.GetArray(xform.BBox)
+	if !ok {
+		return m, false
+	}
+	bbox, err := model.NewPdfRectangle(*arr)
+	if err != nil {
+		return m, false
+	}
+
+	// The bounding box of the form's bounding box transformed by its matrix.
+	llx, lly := math.Inf(1), math.Inf(1)
+	urx, ury := math.Inf(-1), math.Inf(-1)
+	for _, c := range [][2]float64{
+		{bbox.Llx, bbox.Lly}, {bbox.Llx, bbox.Ury}, {bbox.Urx, bbox.Lly}, {bbox.Urx, bbox.Ury},
+	} {
+		x, y := m.Transform(c[0], c[1])
+		llx, lly = math.Min(llx, x), math.Min(lly, y)
+		urx, ury = math.Max(urx, x), math.Max(ury, y)
+	}
+	if urx-llx <= 0 || ury-lly <= 0 {
+		return m, false
+	}
+
+	// `a` maps the transformed bounding box onto the annotation rectangle.
+	sx := (rect.Urx - rect.Llx) / (urx - llx)
+	sy := (rect.Ury - rect.Lly) / (ury - lly)
+	a := transform.NewMatrix(sx, 0, 0, sy, rect.Llx-llx*sx, rect.Lly-lly*sy)
+	return a.Mult(m), true
+}
@@ -0,0 +1,132 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package extractor
+
+import (
+	"math"
+	"sort"
+	"strings"
+)
+
+// TextXYCut returns the text on the page in the order given by the recursive XY-cut algorithm.
+// The page is split at horizontal bands of whitespace into blocks ordered top to bottom, and at
+// vertical bands of whitespace into blocks ordered left to right, recursively until no block can
+// be split further. The text of each block is then extracted line by line as in Text().
+// This order is predictable on simple layouts and is an alternative to Text() for pages where
+// Text() orders the text unexpectedly.
+func (pt PageText) TextXYCut() string {
+	lineSep := pt.options.lineSeparator()
+	var texts []string
+	for _, marks := range xyCut(pt.marks) {
+		block := PageText{marks: marks, options: pt.options}
+		block.computeViews()
+		if block.viewText != "" {
+			texts = append(texts, block.viewText)
+		}
+	}
+	return strings.Join(texts, lineSep)
+}
+
+const (
+	// xyCutGapY is the minimum height of a horizontal band of whitespace that XY-cut splits blocks
+	// at, as a fraction of the blocks' median text height. This is more than the gap between
+	// successive lines of text so that paragraphs are not split into lines.
+	xyCutGapY = 1.0
+	// xyCutGapX is the minimum width of a vertical band of whitespace that XY-cut splits blocks at,
+	// as a fraction of the blocks' median text height. This is more than the gap between words.
+	xyCutGapX = 1.5
+)
+
+// xyCut returns `marks` split into blocks by the recursive XY-cut algorithm. The blocks are
+// returned in reading order.
+func xyCut(marks []textMark) [][]textMark {
+	height := medianHeight(marks)
+	if height <= 0 {
+		return [][]textMark{marks}
+	}
+	parts := cutMarks(marks, true, xyCutGapY*height)
+	if len(parts) <= 1 {
+		parts = cutMarks(marks, false, xyCutGapX*height)
+	}
+	if len(parts) <= 1 {
+		return [][]textMark{marks}
+	}
+	var blocks [][]textMark
+	for _, part := range parts {
+		blocks = append(blocks, xyCut(part)...)
+	}
+	return blocks
+}
+
+// cutMarks splits `marks` at the bands of whitespace at least `minGap` wide that run across all
+// of them. The bands are horizontal and the parts are ordered top to bottom if `horizontal` is
+// true. Otherwise the bands are vertical and the parts are ordered left to right.
+// Space marks don't block bands and are assigned to the parts that contain their centers.
+func cutMarks(marks []textMark, horizontal bool, minGap float64) [][]textMark {
+	// Measure positions along the cut axis so that reading order is increasing order.
+	span := func(tm textMark) (float64, float64) {
+		if horizontal {
+			return -tm.bbox.Ury, -tm.bbox.Lly
+		}
+		return tm.bbox.Llx, tm.bbox.Urx
+	}
+
+	type interval struct{ lo, hi float64 }
+	var intervals []interval
+	for _, tm := range marks {
+		if isTextSpace(tm.text) {
+			continue
+		}
+		lo, hi := span(tm)
+		intervals = append(intervals, interval{lo, hi})
+	}
+	sort.Slice(intervals, func(i, j int) bool { return intervals[i].lo < intervals[j].lo })
+
+	// cuts are the centers of the gaps between the intervals.
+	var cuts []float64
+	for i, iv := range intervals {
+		if i == 0 {
+			continue
+		}
+		hi := intervals[i-1].hi
+		if iv.lo-hi >= minGap {
+			cuts = append(cuts, (hi+iv.lo)/2)
+		}
+		intervals[i].hi = math.Max(iv.hi, hi)
+	}
+	if len(cuts) == 0 {
+		return [][]textMark{marks}
+	}
+
+	parts := make([][]textMark, len(cuts)+1)
+	for _, tm := range marks {
+		lo, hi := span(tm)
+		i := sort.SearchFloat64s(cuts, (lo+hi)/2)
+		parts[i] = append(parts[i], tm)
+	}
+	var nonEmpty [][]textMark
+	for _, part := range parts {
+		if len(part) > 0 {
+			nonEmpty = append(nonEmpty, part)
+		}
+	}
+	return nonEmpty
+}
+
+// medianHeight returns the median height of the non-space marks in `marks`.
+func medianHeight(marks []textMark) float64 {
+	var heights []float64
+	for _, tm := range marks {
+		if !isTextSpace(tm.text) {
+			heights = append(heights, tm.height)
+		}
+	}
+	if len(heights) == 0 {
+		return 0
+	}
+	sort.Float64s(heights)
+	return heights[len(heights)/2]
+}
@@ -0,0 +1,65 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package extractor
+
+import (
+	"testing"
+)
+
+// TestTextXYCut checks that PageText.TextXYCut() orders the text of a titled two column page
+// by column.
+func TestTextXYCut(t *testing.T) {
+	contents := `
+        BT
+        /UniDocCourier 10 Tf
+        1 0 0 1 100 750 Tm (Title) Tj
+        1 0 0 1 10 700 Tm (Left one) Tj
+        1 0 0 1 10 688 Tm (Left two) Tj
+        1 0 0 1 10 676 Tm (Left three) Tj
+        1 0 0 1 300 700 Tm (Right one) Tj
+        1 0 0 1 300 688 Tm (Right two) Tj
+        ET`
+	pt := fragmentPageText(t, contents)
+	expected := "Title\nLeft one\nLeft two\nLeft three\nRight one\nRight two"
+	if text := pt.TextXYCut(); text != expected {
+		t.Fatalf("TextXYCut=%q expected %q", text, expected)
+	}
+	if text := pt.Text(); text == expected {
+		t.Fatalf("Text() is expected to interleave the columns. text=%q", text)
+	}
+}
+
+// TestReadingOrderXYCut checks that ExtractOptions.ReadingOrder = ReadingOrderXYCut lays out the
+// page text and marks in XY-cut order.
+func TestReadingOrderXYCut(t *testing.T) {
+	contents := `
+        BT
+        /UniDocCourier 10 Tf
+        1 0 0 1 10 700 Tm (Left one) Tj
+        1 0 0 1 10 688 Tm (Left two) Tj
+        1 0 0 1 300 700 Tm (Right one) Tj
+        1 0 0 1 300 688 Tm (Right two) Tj
+        ET`
+	e := NewFromContents(contents, fragmentResources(), fragmentMediaBox)
+	e.options.ReadingOrder = ReadingOrderXYCut
+	pt, _, _, err := e.ExtractPageText()
+	if err != nil {
+		t.Fatalf("ExtractPageText failed. err=%v", err)
+	}
+	expected := "Left one\nLeft two\nRight one\nRight two"
+	text := pt.Text()
+	if text != expected {
+		t.Fatalf("text=%q expected %q", text, expected)
+	}
+	for _, tm := range pt.Marks().Elements() {
+		if text[tm.Offset:tm.Offset+len(tm.Text)] != tm.Text {
+			t.Fatalf("mark %s inconsistent with text %q", tm, text)
+		}
+	}
+	if lines := pt.Lines(); len(lines) != 4 || lines[2].Text != "Right one" {
+		t.Fatalf("lines=%v expected 4 lines in XY-cut order", lines)
+	}
+}
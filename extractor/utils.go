@@ -11,6 +11,7 @@ import (
 
 	"github.com/unidoc/unipdf/v3/common/license"
 	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/model"
 )
 
 // RenderMode specifies the text rendering mode (Tmode), which determines whether showing text shall cause
@@ -19,13 +20,47 @@ import (
 // (see 8.5.3, "Path-Painting Operators" and 8.5.4, "Clipping Path Operators").
 type RenderMode int
 
-// Render mode type.
+// Render mode type. RenderModes are combinations of these flags. A RenderMode of 0 is invisible
+// text (Tr 3).
 const (
 	RenderModeStroke RenderMode = 1 << iota // Stroke
 	RenderModeFill                          // Fill
 	RenderModeClip                          // Clip
 )
 
+// Invisible returns true if text drawn with render mode `m` is neither filled nor stroked, so it
+// can't be seen. This is the case for Tr 3, which is often used for the OCR text of scanned pages,
+// and Tr 7, which only adds the text to the clipping path.
+func (m RenderMode) Invisible() bool {
+	return m&(RenderModeFill|RenderModeStroke) == 0
+}
+
+// DecodeConfidence tells how reliably the text of a TextMark was decoded from the character codes
+// in the PDF. Text with low confidence may be worth checking with OCR.
+type DecodeConfidence int
+
+const (
+	// DecodeConfidenceNone means that the character code could not be decoded. The text is the
+	// replacement character '\ufffd'.
+	DecodeConfidenceNone DecodeConfidence = iota
+	// DecodeConfidenceLow means that the character code was decoded with StandardEncoding because
+	// the font has no encoding or ToUnicode CMap. This is a guess.
+	DecodeConfidenceLow
+	// DecodeConfidenceMedium means that the character code was decoded with the font's encoding.
+	DecodeConfidenceMedium
+	// DecodeConfidenceHigh means that the character code was decoded with the font's ToUnicode
+	// CMap.
+	DecodeConfidenceHigh
+)
+
+// decodeConfidences maps the ways that character codes are decoded to our confidence in them.
+var decodeConfidences = map[model.CharcodeDecoding]DecodeConfidence{
+	model.CharcodeDecodingMissing:   DecodeConfidenceNone,
+	model.CharcodeDecodingDefault:   DecodeConfidenceLow,
+	model.CharcodeDecodingEncoding:  DecodeConfidenceMedium,
+	model.CharcodeDecodingToUnicode: DecodeConfidenceHigh,
+}
+
 // toFloatXY returns `objs` as 2 floats, if that's what `objs` is, or an error if it isn't.
 func toFloatXY(objs []core.PdfObject) (x, y float64, err error) {
 	if len(objs) != 2 {
@@ -380,6 +380,20 @@ func (cmap *CMap) Type() int {
 	return cmap.ctype
 }
 
+// SystemInfo returns the CIDSystemInfo of the character collection that `cmap` maps codes to.
+func (cmap *CMap) SystemInfo() CIDSystemInfo {
+	return cmap.systemInfo
+}
+
+// IsSingleByteCode returns true if character code `code` is encoded as a single byte in strings
+// decoded with `cmap`.
+func (cmap *CMap) IsSingleByteCode(code CharCode) bool {
+	if cmap.nbits == 8 {
+		return code <= 0xff
+	}
+	return cmap.inCodespace(code, 1)
+}
+
 // Nbits returns 8 bits for simple font CMaps and 16 bits for CID font CMaps.
 func (cmap *CMap) NBits() int {
 	return cmap.nbits
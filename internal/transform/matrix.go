@@ -173,6 +173,12 @@ func (m *Matrix) Unrealistic() bool {
 	return !(goodXxYy || goodXyYx)
 }
 
+// Singular returns true if `m` is singular or so close to singular that it can't be inverted
+// reliably. Singular matrices collapse the plane onto a line or a point.
+func (m *Matrix) Singular() bool {
+	return math.Abs(m[0]*m[4]-m[1]*m[3]) < minDeterminant
+}
+
 // minSafeScale is the minimum matrix scale that is expected to occur in a valid PDF file.
 const minSafeScale = 1e-6
 
@@ -61,3 +61,21 @@ func makeAngleCase(r, theta float64) angleCase {
 	d := a
 	return angleCase{params{a, b, c, d, 0, 0}, theta}
 }
+
+func TestSingular(t *testing.T) {
+	tests := []struct {
+		m        Matrix
+		singular bool
+	}{
+		{IdentityMatrix(), false},
+		{NewMatrix(0, 1, -1, 0, 10, 20), false},
+		{NewMatrix(1, 0, 0, 0, 10, 20), true},
+		{NewMatrix(0, 0, 0, 0, 0, 0), true},
+		{NewMatrix(1, 2, 2, 4, 0, 0), true},
+	}
+	for _, test := range tests {
+		if singular := test.m.Singular(); singular != test.singular {
+			t.Fatalf("m=%s: Singular=%t expected %t", test.m, singular, test.singular)
+		}
+	}
+}
@@ -76,6 +76,12 @@ type PdfAnnotationMarkup struct {
 	ExData       core.PdfObject
 }
 
+// GetMarkup returns the markup fields of `markup`. The contexts of all markup annotations embed
+// PdfAnnotationMarkup, so this lets their markup fields be accessed without knowing their type.
+func (markup *PdfAnnotationMarkup) GetMarkup() *PdfAnnotationMarkup {
+	return markup
+}
+
 // PdfAnnotationText represents Text annotations.
 // (Section 12.5.6.4 p. 402).
 type PdfAnnotationText struct {
@@ -92,6 +92,19 @@ func (font *PdfFont) Subtype() string {
 	return subtype
 }
 
+// IsSingleByteCode returns true if character code `code` is encoded as a single byte in strings
+// shown with `font`. This is always true for simple fonts. Composite fonts have single byte codes
+// only if their CMap defines them.
+func (font *PdfFont) IsSingleByteCode(code textencoding.CharCode) bool {
+	if type0, ok := font.context.(*pdfFontType0); ok {
+		if type0.codeToCID == nil {
+			return false
+		}
+		return type0.codeToCID.IsSingleByteCode(cmap.CharCode(code))
+	}
+	return !font.baseFields().isCIDFont()
+}
+
 // IsCID returns true if the underlying font is CID.
 func (font *PdfFont) IsCID() bool {
 	return font.baseFields().isCIDFont()
@@ -433,13 +446,43 @@ func (font *PdfFont) CharcodesToUnicodeWithStats(charcodes []textencoding.CharCo
 // The int returns are the number of strings and the number of unconvereted codes.
 // NOTE: The number of strings returned is equal to the number of charcodes
 func (font *PdfFont) CharcodesToStrings(charcodes []textencoding.CharCode) ([]string, int, int) {
+	texts, _, numChars, numMisses := font.CharcodesToStringsDecodings(charcodes)
+	return texts, numChars, numMisses
+}
+
+// CharcodeDecoding tells how a character code was decoded to unicode.
+type CharcodeDecoding int
+
+const (
+	// CharcodeDecodingMissing means that the character code could not be decoded.
+	CharcodeDecodingMissing CharcodeDecoding = iota
+	// CharcodeDecodingDefault means that the character code was decoded with StandardEncoding
+	// because the font has no encoding. This is a guess.
+	CharcodeDecodingDefault
+	// CharcodeDecodingEncoding means that the character code was decoded with the font's encoding.
+	CharcodeDecodingEncoding
+	// CharcodeDecodingToUnicode means that the character code was decoded with the font's
+	// ToUnicode CMap.
+	CharcodeDecodingToUnicode
+)
+
+// CharcodesToStringsDecodings works like CharcodesToStrings and also returns how each of
+// `charcodes` was decoded.
+func (font *PdfFont) CharcodesToStringsDecodings(charcodes []textencoding.CharCode) (
+	[]string, []CharcodeDecoding, int, int) {
 	fontBase := font.baseFields()
 	texts := make([]string, 0, len(charcodes))
+	decodings := make([]CharcodeDecoding, 0, len(charcodes))
 	numMisses := 0
+	encodingDecoding := CharcodeDecodingEncoding
+	if simple, ok := font.actualFont().(*pdfFontSimple); ok && !simple.hasEncoding() {
+		encodingDecoding = CharcodeDecodingDefault
+	}
 	for _, code := range charcodes {
 		if fontBase.toUnicodeCmap != nil {
 			if s, ok := fontBase.toUnicodeCmap.CharcodeToUnicode(cmap.CharCode(code)); ok {
 				texts = append(texts, s)
+				decodings = append(decodings, CharcodeDecodingToUnicode)
 				continue
 			}
 		}
@@ -449,6 +492,7 @@ func (font *PdfFont) CharcodesToStrings(charcodes []textencoding.CharCode) ([]st
 		if encoder != nil {
 			if r, ok := encoder.CharcodeToRune(code); ok {
 				texts = append(texts, string(r))
+				decodings = append(decodings, encodingDecoding)
 				continue
 			}
 		}
@@ -458,6 +502,7 @@ func (font *PdfFont) CharcodesToStrings(charcodes []textencoding.CharCode) ([]st
 			code, charcodes, fontBase.isCIDFont(), font, encoder)
 		numMisses++
 		texts = append(texts, cmap.MissingCodeString)
+		decodings = append(decodings, CharcodeDecodingMissing)
 	}
 
 	if numMisses != 0 {
@@ -467,7 +512,7 @@ func (font *PdfFont) CharcodesToStrings(charcodes []textencoding.CharCode) ([]st
 			len(charcodes), numMisses, font)
 	}
 
-	return texts, len(texts), numMisses
+	return texts, decodings, len(texts), numMisses
 }
 
 // CharcodeBytesToUnicode converts PDF character codes `data` to a Go unicode string.
@@ -103,6 +103,12 @@ func (font *pdfFontSimple) Encoder() textencoding.TextEncoder {
 	return enc
 }
 
+// hasEncoding returns true if `font` has an encoding. Encoder() returns a default StandardEncoding
+// encoder for fonts that don't.
+func (font *pdfFontSimple) hasEncoding() bool {
+	return font.encoder != nil || font.std14Encoder != nil
+}
+
 // SetEncoder sets the encoding for the underlying font.
 // TODO(peterwilliams97): Change function signature to SetEncoder(encoder *textencoding.simpleEncoder).
 // TODO(gunnsth): Makes sense if SetEncoder is removed from the interface fonts.Font as proposed in PR #260.
@@ -405,6 +405,34 @@ func (p *PdfPage) GetMediaBox() (*PdfRectangle, error) {
 	return nil, errors.New("media box not defined")
 }
 
+// GetRotate gets the inheritable rotation value, either from the page or a higher up page/pages
+// struct. It returns 0 if no rotation is defined.
+func (p *PdfPage) GetRotate() (int64, error) {
+	if p.Rotate != nil {
+		return *p.Rotate, nil
+	}
+
+	node := p.Parent
+	for node != nil {
+		dict, ok := core.GetDict(node)
+		if !ok {
+			return 0, errors.New("invalid parent objects dictionary")
+		}
+
+		if obj := dict.Get("Rotate"); obj != nil {
+			rotate, ok := core.GetIntVal(obj)
+			if !ok {
+				return 0, errors.New("invalid rotate value")
+			}
+			return int64(rotate), nil
+		}
+
+		node = dict.Get("Parent")
+	}
+
+	return 0, nil
+}
+
 // getParentResources searches for page resources in the parent nodes of the page.
 func (p *PdfPage) getParentResources() (*PdfPageResources, error) {
 	node := p.Parent